@@ -116,14 +116,21 @@ type CodecType uint32
 
 var (
 	H264       = MakeVideoCodecType(avCodecTypeMagic + 1)
+	H265       = MakeVideoCodecType(avCodecTypeMagic + 7)
+	MJPEG      = MakeVideoCodecType(avCodecTypeMagic + 8)
 	AAC        = MakeAudioCodecType(avCodecTypeMagic + 1)
 	PCM_MULAW  = MakeAudioCodecType(avCodecTypeMagic + 2)
 	PCM_ALAW   = MakeAudioCodecType(avCodecTypeMagic + 3)
 	SPEEX      = MakeAudioCodecType(avCodecTypeMagic + 4)
 	NELLYMOSER = MakeAudioCodecType(avCodecTypeMagic + 5)
+	G722       = MakeAudioCodecType(avCodecTypeMagic + 9)
+	G726       = MakeAudioCodecType(avCodecTypeMagic + 10)
+	L16        = MakeAudioCodecType(avCodecTypeMagic + 11) // 16 bit big endian PCM
+	OPUS       = MakeAudioCodecType(avCodecTypeMagic + 12)
 
 	// daneshvar.ho
 	ONVIF_METADATA = MakeMetadataCodecType(avCodecTypeMagic + 6)
+	KLV            = MakeMetadataCodecType(avCodecTypeMagic + 13) // SMPTE 336M
 )
 
 const codecTypeVideoBit = 0x00
@@ -135,6 +142,10 @@ func (self CodecType) String() string {
 	switch self {
 	case H264:
 		return "H264"
+	case H265:
+		return "H265"
+	case MJPEG:
+		return "MJPEG"
 	case AAC:
 		return "AAC"
 	case PCM_MULAW:
@@ -145,8 +156,18 @@ func (self CodecType) String() string {
 		return "SPEEX"
 	case NELLYMOSER:
 		return "NELLYMOSER"
+	case G722:
+		return "G722"
+	case G726:
+		return "G726"
+	case L16:
+		return "L16"
+	case OPUS:
+		return "OPUS"
 	case ONVIF_METADATA: // daneshvar.ho
 		return "ONVIF_METADATA"
+	case KLV:
+		return "KLV"
 	}
 	return ""
 }
@@ -253,6 +274,12 @@ type Packet struct {
 	Time            time.Duration // packet decode time
 	TimeSample      uint32
 	Data            []byte // packet data
+
+	WallClock     time.Time // sender wall clock time of the packet from RTCP, zero until known
+	RecordingTime time.Time // absolute time of the packet in a recording, ONVIF replay
+	Discontinuity bool      // packet does not follow on from the previous one of the stream
+	EndOfSection  bool      // last packet before a gap in the recording, ONVIF replay
+	CleanPoint    bool      // decoding may start at the packet, ONVIF replay
 }
 
 // Raw audio frame.
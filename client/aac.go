@@ -0,0 +1,194 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/fanap-infra/rtsp/codec/aacparser"
+	"github.com/fanap-infra/rtsp/utils/bits"
+	"github.com/fanap-infra/rtsp/utils/bits/pio"
+)
+
+// samples of an AAC access unit, the RTP clock is the sample rate
+const aacFrameSamples = 1024
+
+type aacFrame struct {
+	timestamp uint32
+	data      []byte
+}
+
+// handleAACPayload depacketizes an RTP payload of RFC 3640 into one frame
+// per access unit. The frames are queued and emitted one by one, an access
+// unit fragmented over several packets is emitted once complete.
+func (self *Stream) handleAACPayload(timestamp uint32, packet []byte) (err error) {
+	/*
+		+---------+-----------+-----------+---------------+
+		| RTP     | AU Header | Auxiliary | Access Unit   |
+		| Header  | Section   | Section   | Data Section  |
+		+---------+-----------+-----------+---------------+
+
+		+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+- .. -+-+-+-+-+-+-+-+-+-+
+		|AU-headers-length|AU-header|AU-header|      |AU-header|padding|
+		|                 |   (1)   |   (2)   |      |   (n)   | bits  |
+		+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+- .. -+-+-+-+-+-+-+-+-+-+
+
+		AU-header: AU-size, AU-Index or AU-Index-delta, CTS-flag, CTS-delta,
+		DTS-flag, DTS-delta
+	*/
+	if len(packet) < 2 {
+		err = fmt.Errorf("rtp: aac packet too short")
+		return
+	}
+	headersBits := int(pio.U16BE(packet))
+	headersLen := (headersBits + 7) / 8
+	if 2+headersLen > len(packet) {
+		err = fmt.Errorf("rtp: aac au headers too short")
+		return
+	}
+	data := packet[2+headersLen:]
+
+	sizeLength := self.Sdp.SizeLength
+	indexLength := self.Sdp.IndexLength
+	indexDeltaLength := self.Sdp.IndexDeltaLength
+	if sizeLength == 0 { // AAC-hbr
+		sizeLength, indexLength, indexDeltaLength = 13, 3, 3
+	}
+
+	var sizes []int
+	var indexes []int
+	r := &bits.Reader{R: bytes.NewReader(packet[2 : 2+headersLen])}
+	for pos := 0; pos < headersBits; {
+		var size, index uint
+		indexBits := indexDeltaLength
+		if len(sizes) == 0 {
+			indexBits = indexLength
+		}
+		if pos+sizeLength+indexBits > headersBits {
+			break
+		}
+		if size, err = r.ReadBits(sizeLength); err != nil {
+			return
+		}
+		if index, err = r.ReadBits(indexBits); err != nil {
+			return
+		}
+		pos += sizeLength + indexBits
+		for _, deltaLength := range []int{self.Sdp.CTSDeltaLength, self.Sdp.DTSDeltaLength} {
+			if deltaLength == 0 {
+				continue
+			}
+			var flag uint
+			if flag, err = r.ReadBits(1); err != nil {
+				return
+			}
+			pos++
+			if flag != 0 {
+				if _, err = r.ReadBits(deltaLength); err != nil {
+					return
+				}
+				pos += deltaLength
+			}
+		}
+		sizes = append(sizes, int(size))
+		indexes = append(indexes, int(index))
+	}
+	if len(sizes) == 0 {
+		err = fmt.Errorf("rtp: aac au header missing")
+		return
+	}
+
+	if len(sizes) == 1 && sizes[0] > len(data) {
+		// a fragment of a large access unit, later fragments repeat its size
+		if len(self.aacFrag) == 0 || self.aacFragSize != sizes[0] || self.aacFragTimestamp != timestamp {
+			self.aacFrag = nil
+			self.aacFragSize = sizes[0]
+			self.aacFragTimestamp = timestamp
+		}
+		self.aacFrag = append(self.aacFrag, data...)
+		if len(self.aacFrag) > self.aacFragSize {
+			self.aacFrag = nil
+			err = fmt.Errorf("rtp: aac fragment exceeds au size=%d", self.aacFragSize)
+			return
+		}
+		if len(self.aacFrag) == self.aacFragSize {
+			self.aacFrames = append(self.aacFrames, aacFrame{timestamp: timestamp, data: self.aacFrag})
+			self.aacFrag = nil
+			self.nextAACFrame()
+		}
+		return
+	}
+	self.aacFrag = nil
+
+	// the RTP timestamp is of the first access unit, AU-Index is a serial
+	// number and the AU-Index-delta of the next ones is zero unless they
+	// are interleaved
+	ts := timestamp
+	for i, size := range sizes {
+		if size > len(data) {
+			err = fmt.Errorf("rtp: aac au size=%d exceeds payload", size)
+			return
+		}
+		if i > 0 {
+			ts += uint32((indexes[i] + 1) * aacFrameSamples)
+		}
+		self.aacFrames = append(self.aacFrames, aacFrame{timestamp: ts, data: data[:size]})
+		data = data[size:]
+	}
+	self.nextAACFrame()
+
+	return
+}
+
+// handleLATMPayload depacketizes an RTP payload of RFC 6416. An
+// AudioMuxElement may span several packets, the last has the marker bit.
+func (self *Stream) handleLATMPayload(timestamp uint32, packet []byte, marker bool) (err error) {
+	if len(self.aacFrag) > 0 && self.aacFragTimestamp != timestamp {
+		self.aacFrag = nil
+	}
+	self.aacFrag = append(self.aacFrag, packet...)
+	self.aacFragTimestamp = timestamp
+	if !marker {
+		return
+	}
+
+	element := self.aacFrag
+	self.aacFrag = nil
+	var frames [][]byte
+	if frames, err = aacparser.ParseAudioMuxElement(element, &self.latm, self.Sdp.CPresent); err != nil {
+		if self.latm.NumSubFrames == 0 {
+			// no StreamMuxConfig yet, in band only
+			err = nil
+			return
+		}
+		err = fmt.Errorf("rtp: %s", err)
+		return
+	}
+	if self.CodecData == nil {
+		if self.CodecData, err = aacparser.NewCodecDataFromMPEG4AudioConfig(self.latm.Config); err != nil {
+			err = fmt.Errorf("rtp: latm config invalid: %s", err)
+			return
+		}
+	}
+	for i, frame := range frames {
+		self.aacFrames = append(self.aacFrames, aacFrame{
+			timestamp: timestamp + uint32(i*aacFrameSamples),
+			data:      frame,
+		})
+	}
+	self.nextAACFrame()
+
+	return
+}
+
+// nextAACFrame makes the first queued frame the packet of the stream.
+func (self *Stream) nextAACFrame() {
+	if len(self.aacFrames) == 0 {
+		return
+	}
+	frame := self.aacFrames[0]
+	self.aacFrames = self.aacFrames[1:]
+	self.gotpkt = true
+	self.pkt.Data = frame.data
+	self.pkt.IsAudio = true
+	self.timestamp = frame.timestamp
+}
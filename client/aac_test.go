@@ -0,0 +1,261 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/codec/aacparser"
+)
+
+const testAACSdp = "v=0\r\n" +
+	"o=- 0 0 IN IP4 127.0.0.1\r\n" +
+	"s=test\r\n" +
+	"t=0 0\r\n" +
+	"m=audio 0 RTP/AVP 97\r\n" +
+	"a=rtpmap:97 MPEG4-GENERIC/44100/2\r\n" +
+	"a=fmtp:97 streamtype=5;profile-level-id=1;mode=AAC-hbr;SizeLength=13;IndexLength=3;IndexDeltaLength=3;config=1210\r\n" +
+	"a=control:track1\r\n"
+
+func TestAACDepacketize(t *testing.T) {
+	au1 := []byte{0x21, 0x10, 0x04}
+	au2 := []byte{0x21, 0x10, 0x05, 0x06, 0x07}
+	au3 := []byte{0x21, 0x10, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f}
+
+	auHeader := func(size int) []byte {
+		return []byte{byte(size >> 5), byte(size << 3)}
+	}
+	// two access units in one packet
+	twoAUs := append([]byte{0x00, 0x20}, auHeader(len(au1))...)
+	twoAUs = append(twoAUs, auHeader(len(au2))...)
+	twoAUs = append(twoAUs, au1...)
+	twoAUs = append(twoAUs, au2...)
+	// one access unit in two fragments
+	frag1 := append(append([]byte{0x00, 0x10}, auHeader(len(au3))...), au3[:6]...)
+	frag2 := append(append([]byte{0x00, 0x10}, auHeader(len(au3))...), au3[6:]...)
+
+	handler := func(conn net.Conn, req testRequest) bool {
+		switch req.Method {
+		case "DESCRIBE":
+			writeTestResponse(conn, req, 200, []string{"Content-Type: application/sdp"}, testAACSdp)
+		case "SETUP":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234", "Transport: " + req.Header.Get("Transport")}, "")
+		case "PLAY":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234"}, "")
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(97|0x80, 0, 1000, twoAUs))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(97, 1, 1000+2048, frag1))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(97|0x80, 2, 1000+2048, frag2))
+		case "TEARDOWN":
+			return false
+		default:
+			writeTestResponse(conn, req, 200, nil, "")
+		}
+		return true
+	}
+	srv := newTestServer(t, handler)
+	defer srv.Close()
+
+	cli, err := Dial(srv.Url())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	cli.RtspTimeout = 5 * time.Second
+
+	streams, err := cli.Streams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if streams[0].CodecData.Type() != av.AAC {
+		t.Fatalf("codec=%v", streams[0].CodecData.Type())
+	}
+
+	for i, want := range []struct {
+		data []byte
+		tm   time.Duration
+	}{
+		{au1, 0},
+		{au2, ticksToDuration(1024, 44100)},
+		{au3, ticksToDuration(2048, 44100)},
+	} {
+		pkt, err := cli.ReadPacket()
+		if err != nil {
+			t.Fatalf("packet#%d: %s", i, err)
+		}
+		if string(pkt.Data) != string(want.data) || !pkt.IsAudio || pkt.Time != want.tm {
+			t.Fatalf("packet#%d data=%x time=%v", i, pkt.Data, pkt.Time)
+		}
+	}
+}
+
+func TestAACInterleaved(t *testing.T) {
+	stream := &Stream{}
+	auHeader := func(size, index int) []byte {
+		return []byte{byte(size >> 5), byte(size<<3 | index)}
+	}
+	// AU-Index 5 then AU-Index-delta 1, every other frame of the group
+	packet := append([]byte{0x00, 0x20}, auHeader(1, 5)...)
+	packet = append(packet, auHeader(1, 1)...)
+	packet = append(packet, 0xaa, 0xbb)
+	if err := stream.handleAACPayload(1000, packet); err != nil {
+		t.Fatal(err)
+	}
+	if !stream.gotpkt || stream.timestamp != 1000 || len(stream.aacFrames) != 1 || stream.aacFrames[0].timestamp != 1000+2*1024 {
+		t.Fatalf("timestamp=%d frames=%+v", stream.timestamp, stream.aacFrames)
+	}
+}
+
+const testLATMSdp = "v=0\r\n" +
+	"o=- 0 0 IN IP4 127.0.0.1\r\n" +
+	"s=test\r\n" +
+	"t=0 0\r\n" +
+	"m=audio 0 RTP/AVP 98\r\n" +
+	"a=rtpmap:98 MP4A-LATM/44100/2\r\n" +
+	"a=fmtp:98 profile-level-id=15;object=2;cpresent=0;config=400024203FC0\r\n" +
+	"a=control:track1\r\n"
+
+func TestLATMDepacketize(t *testing.T) {
+	frame1 := make([]byte, 300)
+	for i := range frame1 {
+		frame1[i] = byte(i)
+	}
+	frame2 := []byte{0x21, 0x10, 0x04}
+
+	// PayloadLengthInfo is 255 per full byte, element 1 spans two packets
+	element1 := append([]byte{255, 300 - 255}, frame1...)
+	element2 := append([]byte{byte(len(frame2))}, frame2...)
+
+	handler := func(conn net.Conn, req testRequest) bool {
+		switch req.Method {
+		case "DESCRIBE":
+			writeTestResponse(conn, req, 200, []string{"Content-Type: application/sdp"}, testLATMSdp)
+		case "SETUP":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234", "Transport: " + req.Header.Get("Transport")}, "")
+		case "PLAY":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234"}, "")
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(98, 0, 1000, element1[:100]))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(98|0x80, 1, 1000, element1[100:]))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(98|0x80, 2, 1000+1024, element2))
+		case "TEARDOWN":
+			return false
+		default:
+			writeTestResponse(conn, req, 200, nil, "")
+		}
+		return true
+	}
+	srv := newTestServer(t, handler)
+	defer srv.Close()
+
+	cli, err := Dial(srv.Url())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	cli.RtspTimeout = 5 * time.Second
+
+	streams, err := cli.Streams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	codec, ok := streams[0].CodecData.(aacparser.CodecData)
+	if !ok || codec.SampleRate() != 44100 || codec.ChannelLayout().Count() != 2 || codec.Config.ObjectType != 2 {
+		t.Fatalf("codec=%v", streams[0].CodecData)
+	}
+
+	for i, want := range []struct {
+		data []byte
+		tm   time.Duration
+	}{
+		{frame1, 0},
+		{frame2, ticksToDuration(1024, 44100)},
+	} {
+		pkt, err := cli.ReadPacket()
+		if err != nil {
+			t.Fatalf("packet#%d: %s", i, err)
+		}
+		if string(pkt.Data) != string(want.data) || !pkt.IsAudio || pkt.Time != want.tm {
+			t.Fatalf("packet#%d data=%x time=%v", i, pkt.Data, pkt.Time)
+		}
+	}
+}
+
+const testLATMInbandSdp = "v=0\r\n" +
+	"o=- 0 0 IN IP4 127.0.0.1\r\n" +
+	"s=test\r\n" +
+	"t=0 0\r\n" +
+	"m=audio 0 RTP/AVP 98\r\n" +
+	"a=rtpmap:98 MP4A-LATM/44100/2\r\n" +
+	"a=fmtp:98 profile-level-id=15;object=2\r\n" +
+	"a=control:track1\r\n"
+
+func TestLATMInbandConfig(t *testing.T) {
+	frame := make([]byte, 400)
+	for i := range frame {
+		frame[i] = byte(i)
+	}
+
+	packBits := func(bitstr string) []byte {
+		b := make([]byte, (len(bitstr)+7)/8)
+		for i, c := range bitstr {
+			if c == '1' {
+				b[i/8] |= 0x80 >> uint(i%8)
+			}
+		}
+		return b
+	}
+	// PayloadLengthInfo, 255 per full byte
+	payload := fmt.Sprintf("%08b%08b", 255, len(frame)-255)
+	for _, b := range frame {
+		payload += fmt.Sprintf("%08b", b)
+	}
+	// useSameStreamMux=0 then the StreamMuxConfig 400024203FC0 (44 bits),
+	// the payload is not byte aligned
+	element := packBits("0" + "01000000000000000010010000100000001111111100" + payload)
+	sameMux := packBits("1" + payload)
+
+	handler := func(conn net.Conn, req testRequest) bool {
+		switch req.Method {
+		case "DESCRIBE":
+			writeTestResponse(conn, req, 200, []string{"Content-Type: application/sdp"}, testLATMInbandSdp)
+		case "SETUP":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234", "Transport: " + req.Header.Get("Transport")}, "")
+		case "PLAY":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234"}, "")
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(98|0x80, 0, 1000, element))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(98|0x80, 1, 1000+1024, sameMux))
+		case "TEARDOWN":
+			return false
+		default:
+			writeTestResponse(conn, req, 200, nil, "")
+		}
+		return true
+	}
+	srv := newTestServer(t, handler)
+	defer srv.Close()
+
+	cli, err := Dial(srv.Url())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	cli.RtspTimeout = 5 * time.Second
+
+	streams, err := cli.Streams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	codec, ok := streams[0].CodecData.(aacparser.CodecData)
+	if !ok || codec.SampleRate() != 44100 || codec.ChannelLayout().Count() != 2 {
+		t.Fatalf("codec=%v", streams[0].CodecData)
+	}
+	// the element carrying the config was read while probing
+	pkt, err := cli.ReadPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(pkt.Data) != string(frame) || pkt.Time != ticksToDuration(1024, 44100) {
+		t.Fatalf("data=%x time=%v", pkt.Data, pkt.Time)
+	}
+}
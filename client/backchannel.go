@@ -0,0 +1,126 @@
+package client
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/packetizer"
+)
+
+// onvifBackchannelTag is the feature tag asking an ONVIF device to add its
+// audio output to the session description.
+const onvifBackchannelTag = "www.onvif.org/ver20/backchannel"
+
+// backchannelTags returns the Require feature tags the backchannel adds to
+// method.
+func (self *Client) backchannelTags(method string) (tags []string) {
+	if !self.Backchannel {
+		return
+	}
+	switch method {
+	case "DESCRIBE", "SETUP", "PLAY":
+		tags = append(tags, onvifBackchannelTag)
+	}
+	return
+}
+
+func isBackchannel(stream *Stream) bool {
+	return stream.Sdp.Direction == "sendonly"
+}
+
+// setupBackchannel sets up the first backchannel track, interleaved on the
+// RTSP connection like the other tracks.
+func (self *Client) setupBackchannel() (err error) {
+	for si, stream := range self.streams {
+		if !isBackchannel(stream) {
+			continue
+		}
+		if self.transport != TransportTCP {
+			err = fmt.Errorf("rtsp: backchannel requires tcp transport")
+			return
+		}
+		if stream.CodecData == nil {
+			err = fmt.Errorf("rtsp: backchannel codec=%v unsupported", stream.Sdp.Type)
+			return
+		}
+		if stream.packetizer, err = packetizer.New(stream.Sdp, stream.CodecData, self.MTU); err != nil {
+			return
+		}
+
+		uri := stream.Sdp.Control
+		if !isAbsoluteUri(uri) {
+			uri = self.requestUri + "/" + uri
+		}
+		req := Request{Method: "SETUP", Uri: uri}
+		req.Header = append(req.Header, fmt.Sprintf("Transport: RTP/AVP/TCP;unicast;interleaved=%d-%d", si*2, si*2+1))
+		if self.session != "" {
+			req.Header = append(req.Header, "Session: "+self.session)
+		}
+		if err = self.WriteRequest(req); err != nil {
+			return
+		}
+		var res Response
+		if res, err = self.ReadResponse(); err != nil {
+			return
+		}
+		if res.StatusCode != 200 {
+			err = fmt.Errorf("rtsp: backchannel Setup failed, StatusCode=%d", res.StatusCode)
+			return
+		}
+		self.backchannel = &BackchannelWriter{client: self, stream: stream, channel: si * 2}
+		return
+	}
+	return
+}
+
+// BackchannelWriter sends audio to the device over the ONVIF backchannel.
+type BackchannelWriter struct {
+	client  *Client
+	stream  *Stream
+	channel int
+}
+
+// BackchannelWriter returns the writer for the backchannel track once the
+// session is playing. Client.Backchannel must be set before the first
+// request, and the device must have offered a G.711 or AAC backchannel.
+func (self *Client) BackchannelWriter() (w *BackchannelWriter, err error) {
+	if !self.Backchannel {
+		err = fmt.Errorf("rtsp: backchannel not requested")
+		return
+	}
+	if err = self.prepare(stageCodecDataDone); err != nil {
+		return
+	}
+	if self.backchannel == nil {
+		err = fmt.Errorf("rtsp: no backchannel offered")
+		return
+	}
+	w = self.backchannel
+	return
+}
+
+// CodecData is the format the device expects, packets written must match it.
+func (self *BackchannelWriter) CodecData() av.CodecData {
+	return self.stream.CodecData
+}
+
+// WritePacket packetizes an audio frame and sends it interleaved on the RTSP
+// connection. It may be called while another goroutine reads packets.
+func (self *BackchannelWriter) WritePacket(pkt av.Packet) (err error) {
+	var packets [][]byte
+	if packets, err = self.stream.packetizer.Packetize(pkt); err != nil {
+		return
+	}
+	for _, b := range packets {
+		frame := make([]byte, 4+len(b))
+		frame[0] = '$'
+		frame[1] = byte(self.channel)
+		binary.BigEndian.PutUint16(frame[2:4], uint16(len(b)))
+		copy(frame[4:], b)
+		if err = self.client.write(frame); err != nil {
+			return
+		}
+	}
+	return
+}
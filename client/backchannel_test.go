@@ -0,0 +1,164 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fanap-infra/rtsp/av"
+)
+
+const testBackchannelSdp = testSdp +
+	"m=audio 0 RTP/AVP 8\r\n" +
+	"a=rtpmap:8 PCMA/8000\r\n" +
+	"a=control:track2\r\n" +
+	"a=sendonly\r\n"
+
+func TestBackchannel(t *testing.T) {
+	payload := []byte{1, 2, 3, 4}
+	errs := make(chan error, 4)
+	received := make(chan []byte, 1)
+
+	handler := func(conn net.Conn, req testRequest) bool {
+		switch req.Method {
+		case "DESCRIBE", "SETUP", "PLAY":
+			if req.Header.Get("Require") != onvifBackchannelTag {
+				errs <- fmt.Errorf("%s without Require backchannel", req.Method)
+			}
+		}
+		switch req.Method {
+		case "DESCRIBE":
+			writeTestResponse(conn, req, 200, []string{"Content-Type: application/sdp"}, testBackchannelSdp)
+		case "SETUP":
+			transport := req.Header.Get("Transport")
+			if strings.HasSuffix(req.Uri, "track2") && !strings.Contains(transport, "interleaved=2-3") {
+				errs <- fmt.Errorf("backchannel transport=%s", transport)
+			}
+			writeTestResponse(conn, req, 200, []string{"Session: 1234", "Transport: " + transport}, "")
+		case "PLAY":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234"}, "")
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(0, 0, 1000, payload))
+		case "TEARDOWN":
+			return false
+		default:
+			writeTestResponse(conn, req, 200, nil, "")
+		}
+		return true
+	}
+	srv := newTestServer(t, handler)
+	defer srv.Close()
+	srv.onInterleaved = func(channel int, packet []byte) {
+		if channel == 2 {
+			received <- packet
+		}
+	}
+
+	cli, err := Dial(srv.Url())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	cli.RtspTimeout = 5 * time.Second
+	cli.Backchannel = true
+
+	streams, err := cli.Streams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(streams) != 1 {
+		t.Fatalf("streams=%d, backchannel must not be read from", len(streams))
+	}
+	w, err := cli.BackchannelWriter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ := w.CodecData().Type(); typ != av.PCM_ALAW {
+		t.Fatalf("backchannel codec=%v", typ)
+	}
+
+	audio := bytes.Repeat([]byte{0xd5}, 160)
+	if err = w.WritePacket(av.Packet{Data: audio}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case packet := <-received:
+		if packet[1]&0x7f != 8 || !bytes.Equal(packet[12:], audio) {
+			t.Fatalf("backchannel packet=%x", packet[:12])
+		}
+	case err = <-errs:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no backchannel packet")
+	}
+}
+
+func TestSendonlyWithoutBackchannel(t *testing.T) {
+	handler := func(conn net.Conn, req testRequest) bool {
+		switch req.Method {
+		case "DESCRIBE":
+			writeTestResponse(conn, req, 200, []string{"Content-Type: application/sdp"}, testBackchannelSdp)
+		case "SETUP":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234", "Transport: " + req.Header.Get("Transport")}, "")
+		case "TEARDOWN":
+			return false
+		default:
+			writeTestResponse(conn, req, 200, nil, "")
+		}
+		return true
+	}
+	srv := newTestServer(t, handler)
+	defer srv.Close()
+
+	cli, err := Dial(srv.Url())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	cli.RtspTimeout = 5 * time.Second
+
+	// some servers mark ordinary media sendonly, it is set up as usual
+	streams, err := cli.Streams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(streams) != 2 {
+		t.Fatalf("streams=%d", len(streams))
+	}
+}
+
+func TestRequireReplayAndBackchannel(t *testing.T) {
+	requires := make(chan []string, 1)
+	handler := func(conn net.Conn, req testRequest) bool {
+		switch req.Method {
+		case "DESCRIBE":
+			requires <- req.Header["Require"]
+			writeTestResponse(conn, req, 200, []string{"Content-Type: application/sdp"}, testBackchannelSdp)
+		case "TEARDOWN":
+			return false
+		default:
+			writeTestResponse(conn, req, 200, nil, "")
+		}
+		return true
+	}
+	srv := newTestServer(t, handler)
+	defer srv.Close()
+
+	cli, err := Dial(srv.Url())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	cli.RtspTimeout = 5 * time.Second
+	cli.Replay = &ReplayConfig{}
+	cli.Backchannel = true
+
+	if _, err = cli.Describe(); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-requires; len(got) != 1 || got[0] != "onvif-replay, "+onvifBackchannelTag {
+		t.Fatalf("require=%q", got)
+	}
+}
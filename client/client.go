@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/md5"
+	"crypto/tls"
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/hex"
@@ -23,8 +24,9 @@ import (
 	"github.com/fanap-infra/rtsp/codec"
 	"github.com/fanap-infra/rtsp/codec/aacparser"
 	"github.com/fanap-infra/rtsp/codec/h264parser"
+	"github.com/fanap-infra/rtsp/codec/h265parser"
+	"github.com/fanap-infra/rtsp/codec/klv"
 	"github.com/fanap-infra/rtsp/sdp"
-	"github.com/fanap-infra/rtsp/utils/bits/pio"
 )
 
 var ErrCodecDataChange = fmt.Errorf("rtsp: codec data change, please call HandleCodecDataChange()")
@@ -52,6 +54,47 @@ type Client struct {
 
 	SkipErrRtpBlock bool
 
+	// Transport selects how media is requested in SETUP, TransportTCP by default.
+	Transport Transport
+	// UDPFallbackTimeout is how long TransportAuto waits for the first RTP
+	// packet over UDP after PLAY before switching to TCP.
+	UDPFallbackTimeout time.Duration
+	// MulticastInterface is the interface used to join groups with
+	// TransportUDPMulticast, nil selects the system default.
+	MulticastInterface *net.Interface
+
+	// Replay plays back recordings of ONVIF Profile G devices, packets
+	// then carry RecordingTime, CleanPoint and the discontinuity flags.
+	Replay *ReplayConfig
+	// Backchannel asks ONVIF devices for their audio output, which is then
+	// set up along with the other tracks, see BackchannelWriter.
+	Backchannel bool
+	// MTU limits the size of the RTP packets sent when publishing or on the
+	// backchannel, packetizer.DefaultMTU when zero.
+	MTU int
+
+	// RtcpInterval is how often receiver reports are sent for each stream,
+	// 5 seconds when zero. Negative disables them.
+	RtcpInterval time.Duration
+	// KeepSEI keeps H264 SEI NAL units in the access units returned.
+	KeepSEI bool
+
+	// JitterBufferDepth is how many packets of a stream may queue behind a
+	// missing one before it is given up, and JitterBufferLatency how long
+	// they may wait, zero leaving either unbounded. With both zero packets
+	// are passed on as they arrive, gaps counted as lost and packets behind
+	// a later one counted as late and dropped, see Stats.
+	JitterBufferDepth   int
+	JitterBufferLatency time.Duration
+
+	// DiscontinuityPolicy handles RTP timestamps that jump or restart,
+	// DiscontinuityRebase by default.
+	DiscontinuityPolicy DiscontinuityPolicy
+	// SyncTime makes av.Packet.Time the wall clock time of the RTCP sender
+	// reports relative to the first packet of any stream, so the streams
+	// line up. Packets of a stream are dropped until its first sender report.
+	SyncTime bool
+
 	RtspTimeout         time.Duration
 	RtpTimeout          time.Duration
 	RtpKeepAliveTimeout time.Duration
@@ -74,13 +117,35 @@ type Client struct {
 	// streamsintf []av.CodecData
 	session string
 	lock    sync.RWMutex
+	wlock   sync.Mutex
 	// body        io.Reader
+
+	transport   Transport
+	udpFallback bool
+	udpBlocks   chan udpBlock
+	udpDone     chan struct{}
+	udpReceived bool
+
+	secure     bool
+	tunnel     bool
+	publishing bool
+	dialConn   func() (net.Conn, error)
+
+	backchannel *BackchannelWriter
+
+	rtcpSSRC       uint32
+	rtcpEpoch      time.Time
+	rtcpReportTime time.Time
+	syncRef        time.Time
+
+	dueBlocks [][]byte
 }
 
 type Request struct {
 	Header []string
 	Uri    string
 	Method string
+	Body   []byte
 }
 
 type Response struct {
@@ -92,23 +157,99 @@ type Response struct {
 	Block []byte
 }
 
-func dial(ctx context.Context, uri string, timeout time.Duration) (self *Client, err error) {
-	if !strings.HasPrefix(uri, "rtsp://") {
-		return nil, fmt.Errorf("RTSP doesn't support protocol: %s", uri)
+// DialOptions configures how the RTSP connection is established.
+type DialOptions struct {
+	// Timeout bounds connecting and, for rtsps://, the TLS handshake.
+	Timeout time.Duration
+
+	// TLSConfig is used for rtsps:// URIs, ServerName defaults to the URI host.
+	TLSConfig *tls.Config
+	// InsecureSkipVerify accepts any server certificate, for lab cameras
+	// with self-signed certificates.
+	InsecureSkipVerify bool
+
+	// HTTPTunnel carries RTSP over the QuickTime HTTP tunnel, as an
+	// rtsp+http:// URI does. The port defaults to 80.
+	HTTPTunnel bool
+}
+
+func isAbsoluteUri(uri string) bool {
+	return strings.HasPrefix(uri, "rtsp://") || strings.HasPrefix(uri, "rtsps://")
+}
+
+func dialTLS(ctx context.Context, dailer *net.Dialer, host string, opts DialOptions) (conn net.Conn, err error) {
+	var config *tls.Config
+	if opts.TLSConfig != nil {
+		config = opts.TLSConfig.Clone()
+	} else {
+		config = &tls.Config{}
+	}
+	if config.ServerName == "" {
+		config.ServerName, _, _ = net.SplitHostPort(host)
+	}
+	if opts.InsecureSkipVerify {
+		config.InsecureSkipVerify = true
 	}
 
+	var tcpconn net.Conn
+	if tcpconn, err = dailer.DialContext(ctx, "tcp", host); err != nil {
+		return
+	}
+	if opts.Timeout > 0 {
+		tcpconn.SetDeadline(time.Now().Add(opts.Timeout))
+	}
+	tlsconn := tls.Client(tcpconn, config)
+	if err = tlsconn.Handshake(); err != nil {
+		tcpconn.Close()
+		err = fmt.Errorf("rtsp: tls handshake failed: %s", err)
+		return
+	}
+	tcpconn.SetDeadline(time.Time{})
+	conn = tlsconn
+	return
+}
+
+func dial(ctx context.Context, uri string, opts DialOptions) (self *Client, err error) {
 	var URL *url.URL
 	if URL, err = url.Parse(uri); err != nil {
 		return
 	}
 
+	var defaultPort string
+	switch URL.Scheme {
+	case "rtsp":
+		defaultPort = "554"
+	case "rtsps":
+		defaultPort = "322"
+	case "rtsp+http":
+		opts.HTTPTunnel = true
+	default:
+		return nil, fmt.Errorf("RTSP doesn't support protocol: %s", uri)
+	}
+	if opts.HTTPTunnel {
+		if URL.Scheme == "rtsps" {
+			return nil, fmt.Errorf("rtsp: http tunnel over tls unsupported: %s", uri)
+		}
+		URL.Scheme = "rtsp"
+		defaultPort = "80"
+	}
+
 	if _, _, err := net.SplitHostPort(URL.Host); err != nil {
-		URL.Host = URL.Host + ":554"
+		URL.Host = URL.Host + ":" + defaultPort
 	}
 
-	dailer := net.Dialer{Timeout: timeout}
+	dailer := &net.Dialer{Timeout: opts.Timeout}
+	dialConn := func() (net.Conn, error) {
+		if opts.HTTPTunnel {
+			return dialHTTPTunnel(ctx, dailer, URL.Host, URL.RequestURI())
+		}
+		if URL.Scheme == "rtsps" {
+			return dialTLS(ctx, dailer, URL.Host, opts)
+		}
+		return dailer.DialContext(ctx, "tcp", URL.Host)
+	}
 	var conn net.Conn
-	if conn, err = dailer.DialContext(ctx, "tcp", URL.Host); err != nil {
+	if conn, err = dialConn(); err != nil {
 		return
 	}
 
@@ -123,20 +264,27 @@ func dial(ctx context.Context, uri string, timeout time.Duration) (self *Client,
 		url:             URL,
 		requestUri:      u2.String(),
 		SkipErrRtpBlock: SkipErrRtpBlock,
+		secure:          URL.Scheme == "rtsps",
+		tunnel:          opts.HTTPTunnel,
+		dialConn:        dialConn,
 	}
 	return
 }
 
 func DialTimeout(uri string, timeout time.Duration) (self *Client, err error) {
-	return dial(context.Background(), uri, timeout)
+	return dial(context.Background(), uri, DialOptions{Timeout: timeout})
 }
 
 func Dial(uri string) (self *Client, err error) {
-	return dial(context.Background(), uri, 0)
+	return dial(context.Background(), uri, DialOptions{})
 }
 
 func DialContext(ctx context.Context, uri string) (self *Client, err error) {
-	return dial(ctx, uri, 0)
+	return dial(ctx, uri, DialOptions{})
+}
+
+func DialWithOptions(ctx context.Context, uri string, opts DialOptions) (self *Client, err error) {
+	return dial(ctx, uri, opts)
 }
 
 func (self *Client) allCodecDataReady() bool {
@@ -214,6 +362,13 @@ func (self *Client) SendRtpKeepalive() (err error) {
 			if err = self.WriteRequest(req); err != nil {
 				return
 			}
+			// with TCP the response is picked up by poll() between
+			// interleaved blocks, while publishing Record() drains it
+			if self.transport != TransportTCP && !self.publishing {
+				if _, err = self.ReadResponse(); err != nil {
+					return
+				}
+			}
 		}
 	}
 	return
@@ -239,23 +394,44 @@ func (self *Client) WriteRequest(req Request) (err error) {
 		io.WriteString(buf, s)
 		io.WriteString(buf, "\r\n")
 	}
+	for _, s := range self.replayHeaders(req.Method) {
+		io.WriteString(buf, s)
+		io.WriteString(buf, "\r\n")
+	}
+	// one Require header with the feature tags of replay and backchannel
+	if tags := append(self.replayTags(req.Method), self.backchannelTags(req.Method)...); len(tags) > 0 {
+		fmt.Fprintf(buf, "Require: %s\r\n", strings.Join(tags, ", "))
+	}
 	for _, s := range self.Headers {
 		io.WriteString(buf, s)
 		io.WriteString(buf, "\r\n")
 	}
+	if len(req.Body) > 0 {
+		fmt.Fprintf(buf, "Content-Length: %d\r\n", len(req.Body))
+	}
 	io.WriteString(buf, "\r\n")
+	buf.Write(req.Body)
 
 	bufout := buf.Bytes()
 
 	logRTSP.Debug("> ", string(bufout))
 
-	if _, err = self.conn.Write(bufout); err != nil {
+	if err = self.write(bufout); err != nil {
 		return
 	}
 
 	return
 }
 
+// write keeps requests and backchannel packets written from other
+// goroutines from interleaving.
+func (self *Client) write(b []byte) (err error) {
+	self.wlock.Lock()
+	defer self.wlock.Unlock()
+	_, err = self.conn.Write(b)
+	return
+}
+
 func (self *Client) parseBlockHeader(h []byte) (length int, no int, valid bool) {
 	length = int(h[2])<<8 + int(h[3])
 	no = int(h[1])
@@ -277,16 +453,6 @@ func (self *Client) parseBlockHeader(h []byte) (length int, no int, valid bool)
 		if int(h[5]&0x7f) != stream.Sdp.PayloadType {
 			return
 		}
-
-		timestamp := binary.BigEndian.Uint32(h[8:12])
-		if stream.firsttimestamp != 0 {
-			timestamp -= stream.firsttimestamp
-			if timestamp < stream.timestamp {
-				return
-			} else if timestamp-stream.timestamp > uint32(stream.timeScale()*60*60) {
-				return
-			}
-		}
 	}
 
 	valid = true
@@ -602,7 +768,10 @@ func (self *Client) ReadResponse() (res Response, err error) {
 
 func (self *Client) SetupAll() (err error) {
 	idx := []int{}
-	for i := range self.streams {
+	for i, stream := range self.streams {
+		if !self.publishing && self.Backchannel && isBackchannel(stream) {
+			continue
+		}
 		idx = append(idx, i)
 	}
 	return self.Setup(idx)
@@ -619,29 +788,97 @@ func (self *Client) Setup(idx []int) (err error) {
 	}
 	self.setupIdx = idx
 
-	for i, si := range idx {
+	self.transport = self.Transport
+	if self.transport == TransportAuto {
+		// media stays inside the TLS connection or HTTP tunnel unless UDP
+		// is asked for explicitly
+		if self.udpFallback || self.secure || self.tunnel || self.Backchannel {
+			self.transport = TransportTCP
+		} else {
+			self.transport = TransportUDP
+		}
+	}
+
+	for i := 0; i < len(idx); i++ {
+		si := idx[i]
 		self.setupMap[si] = i
 
 		uri := ""
 		control := self.streams[si].Sdp.Control
 
-		if strings.HasPrefix(control, "rtsp://") {
+		if isAbsoluteUri(control) {
 			uri = control
 		} else {
 			uri = self.requestUri + "/" + control
 		}
 		req := Request{Method: "SETUP", Uri: uri}
-		req.Header = append(req.Header, fmt.Sprintf("Transport: RTP/AVP/TCP;unicast;interleaved=%d-%d", si*2, si*2+1))
+
+		stream := self.streams[si]
+		switch self.transport {
+		case TransportUDP:
+			if stream.udp == nil {
+				if stream.udp, err = newUDPStream(); err != nil {
+					return
+				}
+			}
+			port := stream.udp.rtpPort()
+			req.Header = append(req.Header, fmt.Sprintf("Transport: RTP/AVP;unicast;client_port=%d-%d", port, port+1))
+		case TransportUDPMulticast:
+			req.Header = append(req.Header, "Transport: RTP/AVP;multicast")
+		default:
+			req.Header = append(req.Header, fmt.Sprintf("Transport: RTP/AVP/TCP;unicast;interleaved=%d-%d", si*2, si*2+1))
+		}
+		if self.publishing {
+			req.Header[len(req.Header)-1] += ";mode=record"
+		}
 		if self.session != "" {
 			req.Header = append(req.Header, "Session: "+self.session)
 		}
 		if err = self.WriteRequest(req); err != nil {
 			return
 		}
-		if _, err = self.ReadResponse(); err != nil {
+		var res Response
+		if res, err = self.ReadResponse(); err != nil {
+			return
+		}
+
+		// 461 Unsupported Transport: start over with every stream on TCP
+		if res.StatusCode == 461 && self.Transport == TransportAuto && self.transport == TransportUDP {
+			logRTSP.Info("rtsp: udp transport unsupported, falling back to tcp")
+			self.stopUDP()
+			self.udpFallback = true
+			self.transport = TransportTCP
+			i = -1
+			continue
+		}
+
+		if self.transport != TransportTCP {
+			if res.StatusCode != 200 {
+				err = fmt.Errorf("rtsp: Setup failed, StatusCode=%d", res.StatusCode)
+				return
+			}
+			th := parseTransportHeader(res.Headers.Get("Transport"))
+			if self.transport == TransportUDPMulticast {
+				if stream.udp != nil {
+					stream.udp.close()
+				}
+				if stream.udp, err = newMulticastUDPStream(self.MulticastInterface, th); err != nil {
+					return
+				}
+			}
+			stream.udp.setServer(self.serverIP(th), th)
+		}
+	}
+
+	if self.Backchannel && !self.publishing {
+		if err = self.setupBackchannel(); err != nil {
 			return
 		}
 	}
+	if self.transport != TransportTCP && !self.publishing {
+		self.startUDP()
+	}
+	logRTSP.Debugv("rtsp: transport negotiated", "transport", self.transport)
 
 	if self.stage == stageDescribeDone {
 		self.stage = stageSetupDone
@@ -741,13 +978,14 @@ func (self *Client) HandleCodecDataChange() (_newcli *Client, err error) {
 func (self *Stream) clearCodecDataChange() {
 	self.spsChanged = false
 	self.ppsChanged = false
+	self.jpeg.sizeChanged = false
 }
 
 func (self *Stream) isCodecDataChange() bool {
 	if self.spsChanged && self.ppsChanged {
 		return true
 	}
-	return false
+	return self.jpeg.sizeChanged
 }
 
 func (self *Stream) timeScale() int {
@@ -792,18 +1030,69 @@ func (self *Stream) makeCodecData() (err error) {
 				return
 			}
 
+		case av.H265:
+			if len(self.vps) == 0 && len(media.SpropVPS) > 0 {
+				self.vps = media.SpropVPS[0]
+			}
+			if len(self.sps) == 0 && len(media.SpropSPS) > 0 {
+				self.sps = media.SpropSPS[0]
+			}
+			if len(self.pps) == 0 && len(media.SpropPPS) > 0 {
+				self.pps = media.SpropPPS[0]
+			}
+
+			if len(self.vps) > 0 && len(self.sps) > 0 && len(self.pps) > 0 {
+				if self.CodecData, err = h265parser.NewCodecDataFromVPSAndSPSAndPPS(self.vps, self.sps, self.pps); err != nil {
+					err = fmt.Errorf("rtsp: h265 vps/sps/pps invalid: %s", err)
+					return
+				}
+			} else {
+				err = fmt.Errorf("rtsp: missing h265 vps, sps or pps")
+				return
+			}
+
 		case av.AAC:
+			if media.LATM && media.CPresent && len(media.Config) == 0 {
+				// StreamMuxConfig only in band, the codec data waits for it
+				break
+			}
 			if len(media.Config) == 0 {
 				err = fmt.Errorf("rtsp: aac sdp config missing")
 				return
 			}
+			if media.LATM {
+				if self.latm, err = aacparser.ParseLATMConfig(media.Config); err != nil {
+					err = fmt.Errorf("rtsp: latm sdp config invalid: %s", err)
+					return
+				}
+				if self.CodecData, err = aacparser.NewCodecDataFromMPEG4AudioConfig(self.latm.Config); err != nil {
+					err = fmt.Errorf("rtsp: latm sdp config invalid: %s", err)
+					return
+				}
+				break
+			}
 			if self.CodecData, err = aacparser.NewCodecDataFromMPEG4AudioConfigBytes(media.Config); err != nil {
 				err = fmt.Errorf("rtsp: aac sdp config invalid: %s", err)
 				return
 			}
 			// ToDo: daneshvar.ho
+		case av.MJPEG:
+			self.CodecData = self.mjpegCodecData()
+		case av.G722:
+			self.CodecData = codec.NewG722CodecData()
+		case av.G726:
+			// G726-32 or AAL2-G726-32
+			name := strings.ToUpper(media.EncodingName)
+			bitRate, _ := strconv.Atoi(name[strings.LastIndex(name, "-")+1:])
+			self.CodecData = codec.NewG726CodecData(bitRate*1000, strings.HasPrefix(name, "AAL2-"))
+		case av.L16:
+			self.CodecData = codec.NewL16CodecData(self.timeScale(), media.Channels)
+		case av.OPUS:
+			self.CodecData = codec.NewOpusCodecData(media.Channels)
 		case av.ONVIF_METADATA:
 			self.CodecData = codec.NewMetadataCodecData(media.Control)
+		case av.KLV:
+			self.CodecData = klv.NewCodecData()
 		}
 	} else {
 		switch media.PayloadType {
@@ -813,6 +1102,15 @@ func (self *Stream) makeCodecData() (err error) {
 		case 8:
 			self.CodecData = codec.NewPCMAlawCodecData()
 
+		case 9:
+			self.CodecData = codec.NewG722CodecData()
+
+		case 10, 11:
+			self.CodecData = codec.NewL16CodecData(media.TimeScale, media.Channels)
+
+		case 26:
+			self.CodecData = self.mjpegCodecData()
+
 		default:
 			err = fmt.Errorf("rtsp: PayloadType=%d unsupported", media.PayloadType)
 			return
@@ -869,16 +1167,12 @@ func (self *Stream) handleH264Payload(timestamp uint32, packet []byte) (err erro
 	*/
 	switch {
 	case naluType >= 1 && naluType <= 5:
-		if naluType == 5 {
-			self.pkt.IsKeyFrame = true
+		self.appendH264NALU(timestamp, packet)
+
+	case naluType == 6: // sei
+		if self.client != nil && self.client.KeepSEI {
+			self.appendH264NALU(timestamp, packet)
 		}
-		self.gotpkt = true
-		// raw nalu to avcc
-		b := make([]byte, 4+len(packet))
-		pio.PutU32BE(b[0:4], uint32(len(packet)))
-		copy(b[4:], packet)
-		self.pkt.Data = b
-		self.timestamp = timestamp
 
 	case naluType == 7: // sps
 		// daneshvar.ho
@@ -1044,7 +1338,10 @@ func (self *Stream) handleH264Payload(timestamp uint32, packet []byte) (err erro
 		}
 		return
 
-	case naluType >= 6 && naluType <= 23: // other single NALU packet
+	case naluType >= 9 && naluType <= 23: // other single NALU packet
+		if self.client != nil {
+			logRTP.Tracev("rtp: h264 nalu skipped", "type", naluType)
+		}
 	case naluType == 25: // STAB-B
 	case naluType == 26: // MTAP-16
 	case naluType == 27: // MTAP-24
@@ -1101,6 +1398,24 @@ func (self *Stream) handleRtpPacket(packet []byte) (err error) {
 		err = fmt.Errorf("rtp: packet too short")
 		return
 	}
+	if packet[0]&0x10 != 0 { // X, header extension
+		if payloadOffset+4 > len(packet) {
+			err = fmt.Errorf("rtp: packet too short")
+			return
+		}
+		profile := binary.BigEndian.Uint16(packet[payloadOffset:])
+		extLen := int(binary.BigEndian.Uint16(packet[payloadOffset+2:])) * 4
+		ext := packet[payloadOffset+4:]
+		if extLen > len(ext) {
+			err = fmt.Errorf("rtp: packet too short")
+			return
+		}
+		if replay, ok := parseReplayExtension(profile, ext[:extLen]); ok {
+			self.replay = replay
+			self.gotReplay = true
+		}
+		payloadOffset += 4 + extLen
+	}
 	timestamp := binary.BigEndian.Uint32(packet[4:8])
 	payload := packet[payloadOffset:]
 
@@ -1153,22 +1468,49 @@ func (self *Stream) handleRtpPacket(packet []byte) (err error) {
 		if err = self.handleH264Payload(timestamp, payload); err != nil {
 			return
 		}
+		if packet[1]&0x80 != 0 { // M, last packet of the access unit
+			self.finishH264AU()
+		}
+
+	case av.H265:
+		if err = self.handleH265Payload(timestamp, payload); err != nil {
+			return
+		}
+		if packet[1]&0x80 != 0 {
+			self.finishH264AU()
+		}
+
+	case av.MJPEG:
+		if err = self.handleMJPEGPayload(timestamp, payload, packet[1]&0x80 != 0); err != nil {
+			return
+		}
 
 	case av.AAC:
-		if len(payload) < 4 {
-			err = fmt.Errorf("rtp: aac packet too short")
+		if self.Sdp.LATM {
+			err = self.handleLATMPayload(timestamp, payload, packet[1]&0x80 != 0)
+		} else {
+			err = self.handleAACPayload(timestamp, payload)
+		}
+		if err != nil {
 			return
 		}
-		payload = payload[4:] // TODO: remove this hack
-		self.gotpkt = true
-		self.pkt.Data = payload
-		self.pkt.IsAudio = true
-		self.timestamp = timestamp
-	case av.ONVIF_METADATA:
-		self.gotpkt = true
-		self.pkt.IsMetadata = true
-		self.pkt.Data = payload
-		self.timestamp = timestamp
+
+	case av.ONVIF_METADATA, av.KLV:
+		// an XML document or KLV unit (RFC 6597) may span several packets,
+		// the last has the marker bit
+		if len(self.metadata) > 0 && self.metadataTimestamp != timestamp {
+			self.metadata = nil
+		}
+		self.metadata = append(self.metadata, payload...)
+		self.metadataTimestamp = timestamp
+		if packet[1]&0x80 != 0 {
+			self.gotpkt = true
+			self.pkt.IsMetadata = true
+			self.pkt.Data = self.metadata
+			self.timestamp = timestamp
+			self.metadata = nil
+		}
+
 	default:
 		self.gotpkt = true
 		self.pkt.Data = payload
@@ -1180,15 +1522,48 @@ func (self *Stream) handleRtpPacket(packet []byte) (err error) {
 }
 
 func (self *Client) Play() (err error) {
+	return self.PlayRange("")
+}
+
+// PlayRange starts or resumes playback from rng, a Range header value such
+// as the ones built by NptRange, ClockRange and SmpteRange. An empty rng
+// resumes where the stream was paused. Packets received after a seek start
+// again from time zero.
+func (self *Client) PlayRange(rng string) (err error) {
 	req := Request{
 		Method: "PLAY",
 		Uri:    self.requestUri,
 	}
 	req.Header = append(req.Header, "Session: "+self.session)
+	if rng != "" {
+		req.Header = append(req.Header, "Range: "+rng)
+	}
 	if err = self.WriteRequest(req); err != nil {
 		return
 	}
 
+	// with TCP the response is picked up by poll() between interleaved
+	// blocks, unless seeking, where blocks of the old position still
+	// queued ahead of the response are dropped by ReadResponse
+	if self.transport != TransportTCP || rng != "" {
+		var res Response
+		if res, err = self.ReadResponse(); err != nil {
+			return
+		}
+		if res.StatusCode != 200 {
+			err = fmt.Errorf("rtsp: Play failed, StatusCode=%d", res.StatusCode)
+			return
+		}
+	}
+
+	if rng != "" {
+		for _, stream := range self.streams {
+			stream.resetTime()
+		}
+		self.syncRef = time.Time{}
+		self.dueBlocks = nil
+	}
+
 	if self.allCodecDataReady() {
 		self.stage = stageCodecDataDone
 	} else {
@@ -1197,6 +1572,28 @@ func (self *Client) Play() (err error) {
 	return
 }
 
+// Pause halts delivery until Play or PlayRange is called, media blocks
+// received before the response are dropped.
+func (self *Client) Pause() (err error) {
+	req := Request{
+		Method: "PAUSE",
+		Uri:    self.requestUri,
+	}
+	req.Header = append(req.Header, "Session: "+self.session)
+	if err = self.WriteRequest(req); err != nil {
+		return
+	}
+	var res Response
+	if res, err = self.ReadResponse(); err != nil {
+		return
+	}
+	if res.StatusCode != 200 {
+		err = fmt.Errorf("rtsp: Pause failed, StatusCode=%d", res.StatusCode)
+		return
+	}
+	return
+}
+
 func (self *Client) Teardown() (err error) {
 	req := Request{
 		Method: "TEARDOWN",
@@ -1210,16 +1607,12 @@ func (self *Client) Teardown() (err error) {
 }
 
 func (self *Client) Close() (err error) {
+	self.stopUDP()
 	return self.conn.Conn.Close()
 }
 
 func (self *Client) handleBlock(block []byte) (pkt av.Packet, ok bool, err error) {
 	_, blockno, _ := self.parseBlockHeader(block)
-	if blockno%2 != 0 {
-		logRTP.Debugv("rtsp: rtcp block", "len", len(block)-4)
-		return
-	}
-
 	i := blockno / 2
 	if i >= len(self.streams) {
 		err = fmt.Errorf("rtsp: block no=%d invalid", blockno)
@@ -1227,6 +1620,15 @@ func (self *Client) handleBlock(block []byte) (pkt av.Packet, ok bool, err error
 	}
 	stream := self.streams[i]
 
+	if blockno%2 != 0 {
+		logRTP.Debugv("rtsp: rtcp block", "len", len(block)-4)
+		if rerr := self.handleRtcpPacket(stream, block[4:], time.Now()); rerr != nil {
+			logRTP.Debugv("rtcp: packet invalid", "err", rerr)
+		}
+		return
+	}
+	stream.updateStats(block[4:], time.Now())
+
 	herr := stream.handleRtpPacket(block[4:])
 	if herr != nil {
 		if !self.SkipErrRtpBlock {
@@ -1236,65 +1638,176 @@ func (self *Client) handleBlock(block []byte) (pkt av.Packet, ok bool, err error
 	}
 
 	if stream.gotpkt {
-		/*
-			TODO: sync AV by rtcp NTP timestamp
-			TODO: handle timestamp overflow
-			https://tools.ietf.org/html/rfc3550
-			A receiver can then synchronize presentation of the audio and video packets by relating
-			their RTP timestamps using the timestamp pairs in RTCP SR packets.
-		*/
-		if stream.firsttimestamp == 0 {
-			stream.firsttimestamp = stream.timestamp
-		}
+		return self.emitPacket(i, stream)
+	}
+	return
+}
 
-		ts := stream.timestamp
-		stream.timestamp -= stream.firsttimestamp
+// emitPacket stamps the packet a stream completed with its index and times.
+func (self *Client) emitPacket(i int, stream *Stream) (pkt av.Packet, ok bool, err error) {
+	/*
+		https://tools.ietf.org/html/rfc3550
+		A receiver can then synchronize presentation of the audio and video packets by relating
+		their RTP timestamps using the timestamp pairs in RTCP SR packets.
+	*/
+	ts := stream.timestamp
+	clockRate := stream.timeScale()
+	ext, discontinuity := stream.timeline.extend(ts, stream.stats.ssrc, clockRate)
+	// a recording gap flagged by ONVIF replay
+	replayGap := stream.gotReplay && stream.replay.discontinuity
+
+	if self.SyncTime && !stream.hasWallClock() {
+		logRTP.Tracev("rtp: no sender report yet, packet dropped", "stream", i)
+		stream.pkt = av.Packet{}
+		stream.gotpkt = false
+		return
+	}
 
-		ok = true
-		pkt = stream.pkt
-		pkt.Time = time.Duration(stream.timestamp) * time.Second / time.Duration(stream.timeScale())
-		pkt.Idx = int8(self.setupMap[i])
-		pkt.TimeSample = ts - stream.lastTimeSample
+	pkt = stream.pkt
+	pkt.Time = stream.timeline.time(ext, clockRate)
+	pkt.Idx = int8(self.setupMap[i])
+	pkt.TimeSample = ts - stream.lastTimeSample
 
-		if pkt.Time < stream.lasttime || pkt.Time-stream.lasttime > time.Minute*30 {
+	if discontinuity || replayGap {
+		policy := self.DiscontinuityPolicy
+		if replayGap && policy == DiscontinuityError {
+			policy = DiscontinuityMark
+		}
+		switch policy {
+		case DiscontinuityError:
 			err = fmt.Errorf("rtp: time invalid stream#%d time=%v lasttime=%v", pkt.Idx, pkt.Time, stream.lasttime)
+			stream.pkt = av.Packet{}
+			stream.gotpkt = false
 			return
+		case DiscontinuityRebase:
+			stream.timeline.rebase(ext, stream.lasttime, clockRate)
+			pkt.Time = stream.lasttime
+		}
+		logRTP.Debugv("rtp: timestamp discontinuity", "stream", i, "policy", policy, "time", pkt.Time)
+		pkt.Discontinuity = true
+	}
+
+	if stream.hasWallClock() {
+		pkt.WallClock = stream.wallClock(ts)
+		if self.SyncTime {
+			pkt.Time = self.syncedTime(stream, pkt.WallClock)
 		}
-		stream.lasttime = pkt.Time
-		stream.lastTimeSample = ts
+	}
 
-		logRTP.Tracev("rtp: pktout", "index", pkt.Idx, "time", pkt.Time, "len", len(pkt.Data))
+	ok = true
+	stream.lasttime = pkt.Time
+	stream.lastTimeSample = ts
 
-		stream.pkt = av.Packet{}
-		stream.gotpkt = false
+	if stream.gotReplay {
+		pkt.RecordingTime = stream.replay.time
+		pkt.EndOfSection = stream.replay.endOfSection
+		pkt.CleanPoint = stream.replay.cleanPoint
+		// the flags describe the packet carrying them, not the ones after
+		stream.replay.discontinuity = false
+		stream.replay.endOfSection = false
+		stream.replay.cleanPoint = false
 	}
 
+	logRTP.Tracev("rtp: pktout", "index", pkt.Idx, "time", pkt.Time, "len", len(pkt.Data))
+
+	stream.pkt = av.Packet{}
+	stream.gotpkt = false
 	return
 }
 
+// NegotiatedTransport reports the transport media is actually received with,
+// which for TransportAuto is only known after SETUP (or after the fallback
+// following PLAY).
+func (self *Client) NegotiatedTransport() Transport {
+	return self.transport
+}
+
+// reconnectTCP drops a UDP session that never delivered media and sets the
+// same streams up again, interleaved on a new RTSP connection.
+func (self *Client) reconnectTCP() (err error) {
+	logRTSP.Info("rtsp: no rtp over udp, falling back to tcp")
+	self.stopUDP()
+	self.udpFallback = true
+	self.Teardown()
+	self.conn.Conn.Close()
+
+	var conn net.Conn
+	if conn, err = self.dialConn(); err != nil {
+		return
+	}
+	self.conn = &connWithTimeout{Conn: conn}
+	self.brconn = bufio.NewReaderSize(self.conn, 1024)
+	self.session = ""
+	self.stage = stageDescribeDone
+
+	if err = self.Setup(self.setupIdx); err != nil {
+		return
+	}
+	return self.Play()
+}
+
+func (self *Client) readBlock() (block []byte, err error) {
+	for self.transport != TransportTCP {
+		if self.Transport == TransportAuto && !self.udpReceived {
+			timeout := self.UDPFallbackTimeout
+			if timeout == 0 {
+				timeout = defaultUDPFallbackTimeout
+			}
+			if block, err = self.pollUDP(timeout); err == errUDPTimeout {
+				if err = self.reconnectTCP(); err != nil {
+					return
+				}
+				continue
+			}
+			return
+		}
+		return self.pollUDP(self.RtpTimeout)
+	}
+	for {
+		var res Response
+		if res, err = self.poll(); err != nil {
+			return
+		}
+		if len(res.Block) > 0 {
+			block = res.Block
+			return
+		}
+	}
+}
+
 func (self *Client) readPacket() (pkt av.Packet, err error) {
 	if err = self.SendRtpKeepalive(); err != nil {
 		return
 	}
 
 	for {
-		var res Response
-		for {
-			if res, err = self.poll(); err != nil {
+		var ok bool
+		if pkt, ok, err = self.pendingPacket(); err != nil || ok {
+			return
+		}
+
+		block, due := self.nextBlock(time.Now())
+		if !due {
+			if block, err = self.readBlock(); err != nil {
 				return
 			}
-			if len(res.Block) > 0 {
-				break
-			}
+			self.queueBlock(block, time.Now())
+			continue
 		}
 
-		var ok bool
-		if pkt, ok, err = self.handleBlock(res.Block); err != nil {
+		if pkt, ok, err = self.handleBlock(block); err != nil {
+			return
+		}
+		if err = self.sendReceiverReports(); err != nil {
 			return
 		}
 		if ok {
 			return
 		}
+		if self.allStreamsBye() {
+			err = io.EOF
+			return
+		}
 	}
 }
 
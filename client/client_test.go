@@ -0,0 +1,580 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/codec"
+)
+
+const testSdp = "v=0\r\n" +
+	"o=- 0 0 IN IP4 127.0.0.1\r\n" +
+	"s=test\r\n" +
+	"t=0 0\r\n" +
+	"m=audio 0 RTP/AVP 0\r\n" +
+	"a=rtpmap:0 PCMU/8000\r\n" +
+	"a=control:track1\r\n"
+
+type testRequest struct {
+	Method string
+	Uri    string
+	Header textproto.MIMEHeader
+	Body   []byte
+}
+
+// testServer is a minimal stand-in RTSP server on loopback. The handler
+// writes the response for each request and may push media afterwards.
+type testServer struct {
+	ln      net.Listener
+	scheme  string
+	handler func(conn net.Conn, req testRequest) bool
+
+	onInterleaved func(channel int, packet []byte)
+}
+
+func newTestServer(t *testing.T, handler func(conn net.Conn, req testRequest) bool) *testServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	self := &testServer{ln: ln, scheme: "rtsp", handler: handler}
+	go self.serve()
+	return self
+}
+
+func newTestServerTLS(t *testing.T, config *tls.Config, handler func(conn net.Conn, req testRequest) bool) *testServer {
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	self := &testServer{ln: ln, scheme: "rtsps", handler: handler}
+	go self.serve()
+	return self
+}
+
+func (self *testServer) Url() string {
+	return self.scheme + "://" + self.ln.Addr().String() + "/test"
+}
+
+func (self *testServer) Close() {
+	self.ln.Close()
+}
+
+func (self *testServer) serve() {
+	for {
+		conn, err := self.ln.Accept()
+		if err != nil {
+			return
+		}
+		go self.serveConn(conn)
+	}
+}
+
+func (self *testServer) serveConn(conn net.Conn) {
+	defer conn.Close()
+	self.serveRequests(conn, conn)
+}
+
+// serveRequests reads requests from rd and hands conn, where responses go,
+// to the handler. The two differ only for the HTTP tunnel.
+func (self *testServer) serveRequests(rd io.Reader, conn net.Conn) {
+	br := bufio.NewReader(rd)
+	r := textproto.NewReader(br)
+	for {
+		if b, err := br.Peek(1); err == nil && b[0] == '$' {
+			var h [4]byte
+			if _, err = io.ReadFull(br, h[:]); err != nil {
+				return
+			}
+			b := make([]byte, binary.BigEndian.Uint16(h[2:4]))
+			if _, err = io.ReadFull(br, b); err != nil {
+				return
+			}
+			if self.onInterleaved != nil {
+				self.onInterleaved(int(h[1]), b)
+			}
+			continue
+		}
+		line, err := r.ReadLine()
+		if err != nil {
+			return
+		}
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, " ")
+		if len(fields) < 3 {
+			return
+		}
+		req := testRequest{Method: fields[0], Uri: fields[1]}
+		if req.Header, err = r.ReadMIMEHeader(); err != nil {
+			return
+		}
+		if n, _ := strconv.Atoi(req.Header.Get("Content-Length")); n > 0 {
+			req.Body = make([]byte, n)
+			if _, err = io.ReadFull(r.R, req.Body); err != nil {
+				return
+			}
+		}
+		if !self.handler(conn, req) {
+			return
+		}
+	}
+}
+
+func writeTestResponse(conn net.Conn, req testRequest, status int, headers []string, body string) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "RTSP/1.0 %d X\r\n", status)
+	fmt.Fprintf(buf, "CSeq: %s\r\n", req.Header.Get("CSeq"))
+	for _, h := range headers {
+		fmt.Fprintf(buf, "%s\r\n", h)
+	}
+	if body != "" {
+		fmt.Fprintf(buf, "Content-Length: %d\r\n", len(body))
+	}
+	buf.WriteString("\r\n")
+	buf.WriteString(body)
+	conn.Write(buf.Bytes())
+}
+
+func makeTestRtpPacket(pt byte, seq uint16, timestamp uint32, payload []byte) []byte {
+	b := make([]byte, 12+len(payload))
+	b[0] = 0x80
+	b[1] = pt
+	binary.BigEndian.PutUint16(b[2:4], seq)
+	binary.BigEndian.PutUint32(b[4:8], timestamp)
+	binary.BigEndian.PutUint32(b[8:12], 0x12345678)
+	copy(b[12:], payload)
+	return b
+}
+
+func TestUDPTransport(t *testing.T) {
+	payload := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	var clientPort [2]int
+
+	srv := newTestServer(t, func(conn net.Conn, req testRequest) bool {
+		switch req.Method {
+		case "DESCRIBE":
+			writeTestResponse(conn, req, 200, []string{"Content-Type: application/sdp"}, testSdp)
+		case "SETUP":
+			th := parseTransportHeader(req.Header.Get("Transport"))
+			if th.Protocol != "RTP/AVP" || th.ClientPort[0] == 0 {
+				writeTestResponse(conn, req, 461, nil, "")
+				return true
+			}
+			clientPort = th.ClientPort
+			writeTestResponse(conn, req, 200, []string{
+				"Session: 1234;timeout=60",
+				fmt.Sprintf("Transport: RTP/AVP;unicast;client_port=%d-%d;server_port=6970-6971", clientPort[0], clientPort[1]),
+			}, "")
+		case "PLAY":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234"}, "")
+			rtp, err := net.Dial("udp", fmt.Sprintf("127.0.0.1:%d", clientPort[0]))
+			if err != nil {
+				return false
+			}
+			defer rtp.Close()
+			for i := 0; i < 3; i++ {
+				rtp.Write(makeTestRtpPacket(0, uint16(i), uint32(1000+i*160), payload))
+			}
+		default:
+			writeTestResponse(conn, req, 200, nil, "")
+		}
+		return true
+	})
+	defer srv.Close()
+
+	cli, err := Dial(srv.Url())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	cli.Transport = TransportUDP
+	cli.RtspTimeout = 5 * time.Second
+	cli.RtpTimeout = 5 * time.Second
+
+	streams, err := cli.Streams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(streams) != 1 {
+		t.Fatalf("got %d streams", len(streams))
+	}
+
+	for i := 0; i < 3; i++ {
+		pkt, err := cli.ReadPacket()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(pkt.Data, payload) {
+			t.Fatalf("packet %d payload mismatch: %x", i, pkt.Data)
+		}
+		if want := time.Duration(i) * 20 * time.Millisecond; pkt.Time != want {
+			t.Fatalf("packet %d time=%v want %v", i, pkt.Time, want)
+		}
+	}
+}
+
+func TestParseTransportHeader(t *testing.T) {
+	th := parseTransportHeader("RTP/AVP;multicast;destination=239.1.2.3;port=5000-5001;ttl=16;source=10.0.0.1")
+	if th.Protocol != "RTP/AVP" || !th.Multicast {
+		t.Fatalf("protocol=%q multicast=%v", th.Protocol, th.Multicast)
+	}
+	if th.Destination != "239.1.2.3" || th.Port != [2]int{5000, 5001} || th.TTL != 16 || th.Source != "10.0.0.1" {
+		t.Fatalf("unexpected %+v", th)
+	}
+
+	th = parseTransportHeader("RTP/AVP/TCP;unicast;interleaved=2-3;ssrc=1A2B3C4D;mode=\"PLAY\"")
+	if th.Multicast || th.Interleaved != [2]int{2, 3} || th.SSRC != "1A2B3C4D" || th.Mode != "play" {
+		t.Fatalf("unexpected %+v", th)
+	}
+}
+
+func writeTestInterleaved(conn net.Conn, channel int, packet []byte) {
+	b := make([]byte, 4+len(packet))
+	b[0] = '$'
+	b[1] = byte(channel)
+	binary.BigEndian.PutUint16(b[2:4], uint16(len(packet)))
+	copy(b[4:], packet)
+	conn.Write(b)
+}
+
+// testTCPOnlyHandler answers DESCRIBE/SETUP/PLAY and streams interleaved PCMU,
+// refusing UDP SETUP with refuseUDP or silently accepting it otherwise.
+func testTCPOnlyHandler(refuseUDP bool, payload []byte) func(conn net.Conn, req testRequest) bool {
+	return func(conn net.Conn, req testRequest) bool {
+		switch req.Method {
+		case "DESCRIBE":
+			writeTestResponse(conn, req, 200, []string{"Content-Type: application/sdp"}, testSdp)
+		case "SETUP":
+			transport := req.Header.Get("Transport")
+			th := parseTransportHeader(transport)
+			if th.Protocol != "RTP/AVP/TCP" && refuseUDP {
+				writeTestResponse(conn, req, 461, nil, "")
+				return true
+			}
+			writeTestResponse(conn, req, 200, []string{"Session: 1234", "Transport: " + transport}, "")
+		case "PLAY":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234"}, "")
+			if req.Header.Get("Session") != "1234" {
+				return false
+			}
+			for i := 0; i < 3; i++ {
+				writeTestInterleaved(conn, 0, makeTestRtpPacket(0, uint16(i), uint32(1000+i*160), payload))
+			}
+		case "TEARDOWN":
+			return false
+		default:
+			writeTestResponse(conn, req, 200, nil, "")
+		}
+		return true
+	}
+}
+
+func TestAutoTransportFallback(t *testing.T) {
+	payload := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+	for _, refuseUDP := range []bool{true, false} {
+		srv := newTestServer(t, testTCPOnlyHandler(refuseUDP, payload))
+
+		cli, err := Dial(srv.Url())
+		if err != nil {
+			t.Fatal(err)
+		}
+		cli.Transport = TransportAuto
+		cli.RtspTimeout = 5 * time.Second
+		cli.UDPFallbackTimeout = 200 * time.Millisecond
+
+		pkt, err := cli.ReadPacket()
+		if err != nil {
+			t.Fatalf("refuseUDP=%v: %s", refuseUDP, err)
+		}
+		if !bytes.Equal(pkt.Data, payload) {
+			t.Fatalf("refuseUDP=%v: payload mismatch: %x", refuseUDP, pkt.Data)
+		}
+		if tr := cli.NegotiatedTransport(); tr != TransportTCP {
+			t.Fatalf("refuseUDP=%v: negotiated %v", refuseUDP, tr)
+		}
+
+		cli.Close()
+		srv.Close()
+	}
+}
+
+func makeTestCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "camera"},
+		DNSNames:     []string{"camera"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestRTSPS(t *testing.T) {
+	payload := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	cert := makeTestCertificate(t)
+
+	srv := newTestServerTLS(t, &tls.Config{Certificates: []tls.Certificate{cert}}, testTCPOnlyHandler(true, payload))
+	defer srv.Close()
+
+	pool := x509.NewCertPool()
+	leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+	pool.AddCert(leaf)
+
+	cli, err := DialWithOptions(context.Background(), srv.Url(), DialOptions{
+		Timeout:   5 * time.Second,
+		TLSConfig: &tls.Config{RootCAs: pool, ServerName: "camera"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	cli.Transport = TransportAuto
+	cli.RtspTimeout = 5 * time.Second
+
+	pkt, err := cli.ReadPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pkt.Data, payload) {
+		t.Fatalf("payload mismatch: %x", pkt.Data)
+	}
+	if tr := cli.NegotiatedTransport(); tr != TransportTCP {
+		t.Fatalf("negotiated %v", tr)
+	}
+
+	if _, err = DialWithOptions(context.Background(), srv.Url(), DialOptions{Timeout: 5 * time.Second}); err == nil {
+		t.Fatal("untrusted certificate accepted")
+	}
+}
+
+// testTunnelServer accepts the GET/POST connection pair of the HTTP tunnel
+// and serves RTSP requests decoded from the POST body on the GET connection.
+type testTunnelServer struct {
+	*testServer
+	lock sync.Mutex
+	gets map[string]net.Conn
+}
+
+func newTestTunnelServer(t *testing.T, handler func(conn net.Conn, req testRequest) bool) *testTunnelServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	self := &testTunnelServer{
+		testServer: &testServer{ln: ln, scheme: "rtsp+http", handler: handler},
+		gets:       map[string]net.Conn{},
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go self.serveHTTP(conn)
+		}
+	}()
+	return self
+}
+
+func (self *testTunnelServer) serveHTTP(conn net.Conn) {
+	br := bufio.NewReader(conn)
+	req, err := http.ReadRequest(br)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	cookie := req.Header.Get("x-sessioncookie")
+
+	switch req.Method {
+	case "GET":
+		io.WriteString(conn, "HTTP/1.0 200 OK\r\nContent-Type: application/x-rtsp-tunnelled\r\n\r\n")
+		self.lock.Lock()
+		self.gets[cookie] = conn
+		self.lock.Unlock()
+
+	case "POST":
+		defer conn.Close()
+		self.lock.Lock()
+		get := self.gets[cookie]
+		self.lock.Unlock()
+		if get == nil {
+			return
+		}
+		defer get.Close()
+
+		pr, pw := io.Pipe()
+		go func() {
+			// decode one 4 byte quantum at a time since every request is
+			// encoded, and padded, on its own
+			var quantum [4]byte
+			for {
+				if _, err := io.ReadFull(br, quantum[:]); err != nil {
+					pw.CloseWithError(err)
+					return
+				}
+				b, err := base64.StdEncoding.DecodeString(string(quantum[:]))
+				if err != nil {
+					pw.CloseWithError(err)
+					return
+				}
+				pw.Write(b)
+			}
+		}()
+		self.serveRequests(pr, get)
+	}
+}
+
+func TestHTTPTunnel(t *testing.T) {
+	payload := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+	srv := newTestTunnelServer(t, testTCPOnlyHandler(true, payload))
+	defer srv.Close()
+
+	cli, err := DialTimeout(srv.Url(), 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	cli.Transport = TransportAuto
+	cli.RtspTimeout = 5 * time.Second
+
+	pkt, err := cli.ReadPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pkt.Data, payload) {
+		t.Fatalf("payload mismatch: %x", pkt.Data)
+	}
+	if !strings.HasPrefix(cli.requestUri, "rtsp://") {
+		t.Fatalf("request uri %s", cli.requestUri)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	payload := bytes.Repeat([]byte{0x55}, 160)
+
+	for _, transport := range []Transport{TransportTCP, TransportUDP} {
+		received := make(chan []byte, 16)
+		var announced string
+		var rtp *net.UDPConn
+
+		srv := newTestServer(t, func(conn net.Conn, req testRequest) bool {
+			switch req.Method {
+			case "ANNOUNCE":
+				announced = string(req.Body)
+				writeTestResponse(conn, req, 200, nil, "")
+			case "SETUP":
+				th := parseTransportHeader(req.Header.Get("Transport"))
+				if th.Mode != "record" {
+					writeTestResponse(conn, req, 400, nil, "")
+					return true
+				}
+				resp := req.Header.Get("Transport")
+				if th.Protocol == "RTP/AVP" {
+					var err error
+					if rtp, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}); err != nil {
+						return false
+					}
+					port := rtp.LocalAddr().(*net.UDPAddr).Port
+					resp += fmt.Sprintf(";server_port=%d-%d", port, port+1)
+					go func() {
+						b := make([]byte, 2048)
+						for {
+							n, _, err := rtp.ReadFromUDP(b)
+							if err != nil {
+								return
+							}
+							received <- append([]byte(nil), b[:n]...)
+						}
+					}()
+				}
+				writeTestResponse(conn, req, 200, []string{"Session: 1234", "Transport: " + resp}, "")
+			case "TEARDOWN":
+				writeTestResponse(conn, req, 200, nil, "")
+				return false
+			default:
+				writeTestResponse(conn, req, 200, nil, "")
+			}
+			return true
+		})
+		srv.onInterleaved = func(channel int, packet []byte) {
+			if channel == 0 {
+				received <- packet
+			}
+		}
+
+		cli, err := Dial(srv.Url())
+		if err != nil {
+			t.Fatal(err)
+		}
+		cli.Transport = transport
+		cli.RtspTimeout = 5 * time.Second
+
+		if err = cli.WriteHeader([]av.CodecData{codec.NewPCMMulawCodecData()}); err != nil {
+			t.Fatalf("%v: %s", transport, err)
+		}
+		if !strings.Contains(announced, "PCMU/8000") {
+			t.Fatalf("%v: announced sdp %q", transport, announced)
+		}
+		for _, idx := range []int8{-1, 1} {
+			if err = cli.WritePacket(av.Packet{Idx: idx, Data: payload}); err == nil {
+				t.Fatalf("%v: stream index=%d accepted", transport, idx)
+			}
+		}
+		if err = cli.WritePacket(av.Packet{Idx: 0, Data: payload}); err != nil {
+			t.Fatalf("%v: %s", transport, err)
+		}
+
+		select {
+		case packet := <-received:
+			if packet[1]&0x7f != 0 || !bytes.Equal(packet[12:], payload) {
+				t.Fatalf("%v: rtp packet mismatch %x", transport, packet[:12])
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%v: no rtp packet received", transport)
+		}
+
+		if err = cli.WriteTrailer(); err != nil {
+			t.Fatal(err)
+		}
+		cli.Close()
+		srv.Close()
+		if rtp != nil {
+			rtp.Close()
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/codec/h265parser"
+	"github.com/fanap-infra/rtsp/utils/bits/pio"
+)
+
+// h264AccessUnit collects the NAL units of one picture until the marker bit
+// or a timestamp change ends it. H265 streams use it the same way.
+type h264AccessUnit struct {
+	started   bool
+	timestamp uint32
+	nalus     [][]byte
+	keyFrame  bool
+	// complete but held back behind the packet emitted before it
+	ready bool
+
+	// after a loss the rest of the damaged picture is dropped
+	dropping      bool
+	dropTimestamp uint32
+}
+
+// appendH264NALU adds a slice (or SEI) NAL unit to the access unit of
+// timestamp, the previous access unit is emitted when the timestamp moved
+// on without a marker bit.
+func (self *Stream) appendH264NALU(timestamp uint32, nalu []byte) {
+	au := &self.au
+	if au.dropping {
+		if timestamp == au.dropTimestamp {
+			return
+		}
+		au.dropping = false
+	}
+
+	if au.started && (au.ready || timestamp != au.timestamp) {
+		self.finishH264AU()
+	}
+	if !au.started {
+		au.started = true
+		au.timestamp = timestamp
+	}
+	if self.Sdp.Type == av.H265 {
+		if h265parser.IsKeyFrameNALU(nalu) {
+			au.keyFrame = true
+		}
+	} else if nalu[0]&0x1f == 5 { // IDR
+		au.keyFrame = true
+	}
+	au.nalus = append(au.nalus, nalu)
+}
+
+// finishH264AU emits the access unit as one AVCC packet. If the RTP packet
+// already completed the previous access unit this one is marked ready and
+// emitted by pendingPacket.
+func (self *Stream) finishH264AU() {
+	au := &self.au
+	if !au.started {
+		return
+	}
+	if self.gotpkt {
+		au.ready = true
+		return
+	}
+	size := 0
+	for _, nalu := range au.nalus {
+		size += 4 + len(nalu)
+	}
+	b := make([]byte, size)
+	pos := 0
+	for _, nalu := range au.nalus {
+		pio.PutU32BE(b[pos:], uint32(len(nalu)))
+		copy(b[pos+4:], nalu)
+		pos += 4 + len(nalu)
+	}
+
+	self.gotpkt = true
+	self.pkt.Data = b
+	self.pkt.IsKeyFrame = au.keyFrame
+	self.timestamp = au.timestamp
+	*au = h264AccessUnit{}
+}
+
+// dropH264AU discards the incomplete access unit being assembled and the
+// rest of its packets. With none in progress the loss hit the start of the
+// picture of timestamp, the first packet after the loss, which is dropped
+// instead. A complete access unit held back is kept.
+func (self *Stream) dropH264AU(timestamp uint32) {
+	au := &self.au
+	if au.ready {
+		au.dropping = true
+		au.dropTimestamp = timestamp
+		return
+	}
+	if au.started {
+		timestamp = au.timestamp
+	}
+	*au = h264AccessUnit{
+		dropping:      true,
+		dropTimestamp: timestamp,
+	}
+}
+
+// pendingPacket emits an access unit held back by finishH264AU, or an AAC
+// frame queued after the first of its RTP packet.
+func (self *Client) pendingPacket() (pkt av.Packet, ok bool, err error) {
+	for i, stream := range self.streams {
+		if stream.au.ready && !stream.gotpkt {
+			stream.finishH264AU()
+			return self.emitPacket(i, stream)
+		}
+		if len(stream.aacFrames) > 0 && !stream.gotpkt {
+			stream.nextAACFrame()
+			return self.emitPacket(i, stream)
+		}
+	}
+	return
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fanap-infra/rtsp/codec/h264parser"
+)
+
+func TestH264AccessUnit(t *testing.T) {
+	sei := []byte{0x06, 0x05, 0x01, 0x80}
+	idr1 := []byte{0x65, 0x88, 0x01}
+	idr2 := []byte{0x65, 0x08, 0x02, 0x03, 0x04}
+	p1 := []byte{0x41, 0x9a, 0x01}
+	p2 := []byte{0x41, 0x1a, 0x02}
+
+	handler := func(conn net.Conn, req testRequest) bool {
+		switch req.Method {
+		case "DESCRIBE":
+			writeTestResponse(conn, req, 200, []string{"Content-Type: application/sdp"}, testH264Sdp)
+		case "SETUP":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234", "Transport: " + req.Header.Get("Transport")}, "")
+		case "PLAY":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234"}, "")
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(96, 0, 3000, sei))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(96, 1, 3000, idr1))
+			// second slice of the IDR in FU-A fragments
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(96, 2, 3000, []byte{0x7c, 0x85, 0x08, 0x02}))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(96|0x80, 3, 3000, []byte{0x7c, 0x45, 0x03, 0x04}))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(96|0x80, 4, 6000, p1))
+			// no marker bit, the timestamp change ends the picture
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(96, 5, 9000, p1))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(96, 6, 9000, p2))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(96|0x80, 7, 12000, p1))
+		case "TEARDOWN":
+			return false
+		default:
+			writeTestResponse(conn, req, 200, nil, "")
+		}
+		return true
+	}
+	srv := newTestServer(t, handler)
+	defer srv.Close()
+
+	cli, err := Dial(srv.Url())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	cli.RtspTimeout = 5 * time.Second
+	cli.KeepSEI = true
+
+	for i, want := range []struct {
+		nalus    [][]byte
+		keyFrame bool
+		tm       time.Duration
+	}{
+		{[][]byte{sei, idr1, idr2}, true, 0},
+		{[][]byte{p1}, false, time.Second / 30},
+		{[][]byte{p1, p2}, false, 2 * time.Second / 30},
+		{[][]byte{p1}, false, 3 * time.Second / 30},
+	} {
+		pkt, err := cli.ReadPacket()
+		if err != nil {
+			t.Fatalf("packet#%d: %s", i, err)
+		}
+		nalus, typ := h264parser.SplitNALUs(pkt.Data)
+		if typ != h264parser.NALU_AVCC || len(nalus) != len(want.nalus) {
+			t.Fatalf("packet#%d nalus=%x", i, nalus)
+		}
+		for j := range nalus {
+			if string(nalus[j]) != string(want.nalus[j]) {
+				t.Fatalf("packet#%d nalu#%d=%x", i, j, nalus[j])
+			}
+		}
+		if pkt.IsKeyFrame != want.keyFrame || pkt.Time != want.tm {
+			t.Fatalf("packet#%d keyframe=%v time=%v", i, pkt.IsKeyFrame, pkt.Time)
+		}
+	}
+}
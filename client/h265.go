@@ -0,0 +1,152 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/fanap-infra/rtsp/codec/h265parser"
+)
+
+// handleH265Payload depacketizes an RTP payload of RFC 7798. Access units
+// are assembled the same way as H264.
+func (self *Stream) handleH265Payload(timestamp uint32, packet []byte) (err error) {
+	if len(packet) < 3 {
+		err = fmt.Errorf("rtp: h265 packet too short")
+		return
+	}
+
+	naluType := h265parser.GetNALUType(packet)
+
+	switch {
+	case naluType < 32: // slice
+		self.appendH264NALU(timestamp, packet)
+
+	case naluType == h265parser.NALU_SEI_PREFIX || naluType == h265parser.NALU_SEI_SUFFIX:
+		if self.client != nil && self.client.KeepSEI {
+			self.appendH264NALU(timestamp, packet)
+		}
+
+	case naluType == h265parser.NALU_VPS:
+		self.handleH265ParameterSet(&self.vps, packet, nil)
+
+	case naluType == h265parser.NALU_SPS:
+		self.handleH265ParameterSet(&self.sps, packet, &self.spsChanged)
+
+	case naluType == h265parser.NALU_PPS:
+		self.handleH265ParameterSet(&self.pps, packet, &self.ppsChanged)
+
+	case naluType == h265parser.NALU_AP:
+		/*
+			0                   1                   2                   3
+			0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+			+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+			|                          RTP Header                           |
+			+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+			|   PayloadHdr (Type=48)        |         NALU 1 Size           |
+			+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+			|          NALU 1 HDR           |                               |
+			+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+         NALU 1 Data           |
+			|                   . . .                                       |
+			|                                                               |
+			+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+			|  . . .        | NALU 2 Size                   | NALU 2 HDR    |
+			+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+			| NALU 2 HDR    |                                               |
+			+-+-+-+-+-+-+-+-+              NALU 2 Data                      |
+			|                   . . .                                       |
+			|                               +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+			|                               :...OPTIONAL RTP padding        |
+			+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+			Figure 7: An Example of an AP Packet Containing Two Aggregation
+			Units without the DONL and DOND Fields
+		*/
+		packet = packet[2:]
+		for len(packet) >= 2 {
+			size := int(packet[0])<<8 | int(packet[1])
+			if size+2 > len(packet) {
+				break
+			}
+			if err = self.handleH265Payload(timestamp, packet[2:size+2]); err != nil {
+				return
+			}
+			packet = packet[size+2:]
+		}
+
+	case naluType == h265parser.NALU_FU:
+		/*
+			0                   1                   2                   3
+			0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+			+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+			|    PayloadHdr (Type=49)       |   FU header   | DONL (cond)   |
+			+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-|
+			| DONL (cond)   |                                               |
+			|-+-+-+-+-+-+-+-+                                               |
+			|                         FU payload                            |
+			|                                                               |
+			|                               +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+			|                               :...OPTIONAL RTP padding        |
+			+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+			The FU header:
+			+---------------+
+			|0|1|2|3|4|5|6|7|
+			+-+-+-+-+-+-+-+-+
+			|S|E|  FuType   |
+			+---------------+
+		*/
+		fuHeader := packet[2]
+		isStart := fuHeader&0x80 != 0
+		isEnd := fuHeader&0x40 != 0
+		if isStart {
+			self.fuStarted = true
+			self.fuBuffer = []byte{packet[0]&0x81 | (fuHeader&0x3f)<<1, packet[1]}
+		}
+		if self.fuStarted {
+			self.fuBuffer = append(self.fuBuffer, packet[3:]...)
+			if isEnd {
+				self.fuStarted = false
+				if err = self.handleH265Payload(timestamp, self.fuBuffer); err != nil {
+					return
+				}
+			}
+		}
+
+	case naluType == h265parser.NALU_PACI:
+		if self.client != nil {
+			logRTP.Tracev("rtp: h265 PACI packet skipped")
+		}
+
+	case naluType < 48: // other single NALU packet
+		if self.client != nil {
+			logRTP.Tracev("rtp: h265 nalu skipped", "type", naluType)
+		}
+
+	default:
+		err = fmt.Errorf("rtsp: unsupported H265 naluType=%d", naluType)
+		return
+	}
+
+	return
+}
+
+// handleH265ParameterSet keeps the latest VPS/SPS/PPS in set. The codec data
+// is made once all three are known, a later change is flagged in changed.
+func (self *Stream) handleH265ParameterSet(set *[]byte, nalu []byte, changed *bool) {
+	old := *set
+	*set = nalu
+	if len(old) == 0 {
+		if self.CodecData == nil && len(self.vps) > 0 && len(self.sps) > 0 && len(self.pps) > 0 {
+			self.makeCodecData()
+		}
+		return
+	}
+	if !bytes.Equal(old, nalu) {
+		if changed != nil {
+			*changed = true
+		}
+		if self.client != nil {
+			logRTP.Debugv("rtsp: h265 parameter set changed", "type", h265parser.GetNALUType(nalu))
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/codec/h264parser"
+	"github.com/fanap-infra/rtsp/codec/h265parser"
+)
+
+const testH265Sdp = "v=0\r\n" +
+	"o=- 0 0 IN IP4 127.0.0.1\r\n" +
+	"s=test\r\n" +
+	"t=0 0\r\n" +
+	"m=video 0 RTP/AVP 96\r\n" +
+	"a=rtpmap:96 H265/90000\r\n" +
+	"a=fmtp:96 sprop-vps=QAEMAf//AWAAAAMAkAAAAwAAAwB4mZgJ;sprop-sps=QgEBAWAAAAMAkAAAAwAAAwB4oAPAgBDllmZpJMrgEAAAAwAQAAADAeCA;sprop-pps=RAHBcrRiQA==\r\n" +
+	"a=control:track1\r\n"
+
+func TestH265Depacketize(t *testing.T) {
+	idr1 := []byte{0x26, 0x01, 0xaa}
+	idr2 := []byte{0x26, 0x01, 0xaf, 0x01, 0x02}
+	trail := []byte{0x02, 0x01, 0xd0}
+
+	handler := func(conn net.Conn, req testRequest) bool {
+		switch req.Method {
+		case "DESCRIBE":
+			writeTestResponse(conn, req, 200, []string{"Content-Type: application/sdp"}, testH265Sdp)
+		case "SETUP":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234", "Transport: " + req.Header.Get("Transport")}, "")
+		case "PLAY":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234"}, "")
+			// AP of an IDR slice and a prefix SEI
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(96, 0, 3000, []byte{0x60, 0x01, 0x00, 0x03, 0x26, 0x01, 0xaa, 0x00, 0x04, 0x4e, 0x01, 0x05, 0x06}))
+			// second IDR slice in FU fragments
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(96, 1, 3000, []byte{0x62, 0x01, 0x93, 0xaf, 0x01}))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(96|0x80, 2, 3000, []byte{0x62, 0x01, 0x53, 0x02}))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(96|0x80, 3, 6000, trail))
+		case "TEARDOWN":
+			return false
+		default:
+			writeTestResponse(conn, req, 200, nil, "")
+		}
+		return true
+	}
+	srv := newTestServer(t, handler)
+	defer srv.Close()
+
+	cli, err := Dial(srv.Url())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	cli.RtspTimeout = 5 * time.Second
+
+	streams, err := cli.Streams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	codec, ok := streams[0].CodecData.(h265parser.CodecData)
+	if !ok || codec.Type() != av.H265 || codec.Width() != 1920 || codec.Height() != 1080 {
+		t.Fatalf("codec=%v", streams[0].CodecData)
+	}
+
+	for i, want := range []struct {
+		nalus    [][]byte
+		keyFrame bool
+		tm       time.Duration
+	}{
+		{[][]byte{idr1, idr2}, true, 0},
+		{[][]byte{trail}, false, time.Second / 30},
+	} {
+		pkt, err := cli.ReadPacket()
+		if err != nil {
+			t.Fatalf("packet#%d: %s", i, err)
+		}
+		nalus, typ := h264parser.SplitNALUs(pkt.Data)
+		if typ != h264parser.NALU_AVCC || len(nalus) != len(want.nalus) {
+			t.Fatalf("packet#%d nalus=%x", i, nalus)
+		}
+		for j := range nalus {
+			if string(nalus[j]) != string(want.nalus[j]) {
+				t.Fatalf("packet#%d nalu#%d=%x", i, j, nalus[j])
+			}
+		}
+		if pkt.IsKeyFrame != want.keyFrame || pkt.Time != want.tm {
+			t.Fatalf("packet#%d keyframe=%v time=%v", i, pkt.IsKeyFrame, pkt.Time)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/fanap-infra/rtsp/av"
+)
+
+// StreamStats counts the RTP packets of a stream passing the jitter buffer.
+type StreamStats struct {
+	Received  uint64
+	Lost      uint64 // never arrived
+	Late      uint64 // arrived after their turn had passed, dropped
+	Reordered uint64 // arrived out of order but in time
+}
+
+type jitterEntry struct {
+	seq     uint16
+	block   []byte
+	arrival time.Time
+	gap     bool
+}
+
+// jitterBuffer puts the RTP packets of a stream back in sequence order. A
+// missing packet is waited for until depth packets are queued behind it or
+// the oldest has waited latency, then it is counted as lost. With zero depth
+// and latency packets are passed on as they arrive, a packet arriving after
+// a later one is counted late and dropped.
+type jitterBuffer struct {
+	started bool
+	next    uint16
+	entries []jitterEntry
+	// packets due regardless of sequence, after the sender restarted
+	flushed []jitterEntry
+	stats   StreamStats
+}
+
+// push queues an RTP packet, block is the interleaved block carrying it.
+func (self *jitterBuffer) push(block []byte, now time.Time) {
+	seq := binary.BigEndian.Uint16(block[6:8])
+	entry := jitterEntry{seq: seq, block: block, arrival: now}
+	if !self.started {
+		self.started = true
+		self.next = seq
+	}
+
+	diff := int(int16(seq - self.next))
+	if diff > maxDropout || diff < -maxMisorder {
+		// the sender restarted its sequence, play out what is left
+		self.flushed = append(self.flushed, self.entries...)
+		self.entries = nil
+		self.next = seq
+		entry.gap = true
+		self.entries = append(self.entries, entry)
+		return
+	}
+	if diff < 0 {
+		self.stats.Late++
+		return
+	}
+
+	i := len(self.entries)
+	for i > 0 && int16(self.entries[i-1].seq-seq) > 0 {
+		i--
+	}
+	if i > 0 && self.entries[i-1].seq == seq {
+		// duplicate
+		return
+	}
+	if i < len(self.entries) {
+		self.stats.Reordered++
+	}
+	self.entries = append(self.entries, jitterEntry{})
+	copy(self.entries[i+1:], self.entries[i:])
+	self.entries[i] = entry
+}
+
+// pop returns the next packet that is due, gap tells that packets before it
+// are missing.
+func (self *jitterBuffer) pop(now time.Time, depth int, latency time.Duration) (block []byte, gap bool, ok bool) {
+	if len(self.flushed) > 0 {
+		entry := self.flushed[0]
+		self.flushed = self.flushed[1:]
+		self.stats.Received++
+		return entry.block, entry.gap, true
+	}
+	if len(self.entries) == 0 {
+		return
+	}
+	head := self.entries[0]
+	gap = head.gap
+	if head.seq != self.next {
+		buffering := depth > 0 || latency > 0
+		underDepth := depth == 0 || len(self.entries) <= depth
+		inTime := latency == 0 || now.Sub(head.arrival) < latency
+		if buffering && underDepth && inTime {
+			return
+		}
+		self.stats.Lost += uint64(uint16(head.seq - self.next))
+		gap = true
+	}
+	self.entries = self.entries[1:]
+	self.next = head.seq + 1
+	self.stats.Received++
+	block = head.block
+	ok = true
+	return
+}
+
+// reset forgets the queue after a seek.
+func (self *jitterBuffer) reset() {
+	stats := self.stats
+	*self = jitterBuffer{stats: stats}
+}
+
+// dropPartial discards the access unit being assembled when packets of it
+// were lost, rather than emitting it with a hole. timestamp is of the first
+// packet after the loss, see dropH264AU.
+func (self *Stream) dropPartial(timestamp uint32) {
+	self.fuStarted = false
+	self.fuBuffer = nil
+	self.dropH264AU(timestamp)
+	self.jpeg.started = false
+	self.jpeg.data = nil
+	self.aacFrag = nil
+	self.metadata = nil
+	self.pkt = av.Packet{}
+	self.gotpkt = false
+}
+
+// queueBlock passes a block through the jitter buffer of its stream, RTCP
+// blocks are due at once.
+func (self *Client) queueBlock(block []byte, now time.Time) {
+	_, no, _ := self.parseBlockHeader(block)
+	if no%2 != 0 || no/2 >= len(self.streams) || len(block) < 16 {
+		self.dueBlocks = append(self.dueBlocks, block)
+		return
+	}
+	self.streams[no/2].jitter.push(block, now)
+}
+
+// nextBlock returns the next block due from any jitter buffer.
+func (self *Client) nextBlock(now time.Time) (block []byte, ok bool) {
+	if len(self.dueBlocks) > 0 {
+		block = self.dueBlocks[0]
+		self.dueBlocks = self.dueBlocks[1:]
+		return block, true
+	}
+	for _, stream := range self.streams {
+		var gap bool
+		if block, gap, ok = stream.jitter.pop(now, self.JitterBufferDepth, self.JitterBufferLatency); ok {
+			if gap {
+				logRTP.Debugv("rtp: packets lost", "control", stream.Sdp.Control, "lost", stream.jitter.stats.Lost)
+				stream.dropPartial(binary.BigEndian.Uint32(block[8:12]))
+			}
+			return
+		}
+	}
+	return
+}
+
+// Stats returns the packet counters of the streams, in the order of
+// av.Packet.Idx.
+func (self *Client) Stats() (stats []StreamStats) {
+	for _, si := range self.setupIdx {
+		stats = append(stats, self.streams[si].jitter.stats)
+	}
+	return
+}
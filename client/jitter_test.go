@@ -0,0 +1,166 @@
+package client
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+func testJitterBlock(seq uint16) []byte {
+	b := make([]byte, 4+12)
+	b[0] = '$'
+	b[4] = 0x80
+	binary.BigEndian.PutUint16(b[6:8], seq)
+	return b
+}
+
+func popSeqs(jb *jitterBuffer, now time.Time, depth int, latency time.Duration) (seqs []uint16, gaps []bool) {
+	for {
+		block, gap, ok := jb.pop(now, depth, latency)
+		if !ok {
+			return
+		}
+		seqs = append(seqs, binary.BigEndian.Uint16(block[6:8]))
+		gaps = append(gaps, gap)
+	}
+}
+
+func TestJitterBuffer(t *testing.T) {
+	now := time.Now()
+	var jb jitterBuffer
+	for _, seq := range []uint16{65534, 0, 65535} {
+		jb.push(testJitterBlock(seq), now)
+	}
+	// 65535 is waited for
+	seqs, _ := popSeqs(&jb, now, 4, time.Second)
+	if len(seqs) != 3 || seqs[0] != 65534 || seqs[1] != 65535 || seqs[2] != 0 {
+		t.Fatalf("seqs=%v", seqs)
+	}
+
+	// 1 is missing, given up once more than depth packets queue behind it
+	jb.push(testJitterBlock(2), now)
+	jb.push(testJitterBlock(3), now)
+	if seqs, _ = popSeqs(&jb, now, 2, 0); len(seqs) != 0 {
+		t.Fatalf("seqs=%v", seqs)
+	}
+	jb.push(testJitterBlock(4), now)
+	seqs, gaps := popSeqs(&jb, now, 2, 0)
+	if len(seqs) != 3 || seqs[0] != 2 || !gaps[0] || gaps[1] {
+		t.Fatalf("seqs=%v gaps=%v", seqs, gaps)
+	}
+
+	// or once it waited for latency
+	jb.push(testJitterBlock(6), now)
+	if seqs, _ = popSeqs(&jb, now, 0, time.Second); len(seqs) != 0 {
+		t.Fatalf("seqs=%v", seqs)
+	}
+	if seqs, _ = popSeqs(&jb, now.Add(time.Second), 0, time.Second); len(seqs) != 1 {
+		t.Fatalf("seqs=%v", seqs)
+	}
+
+	jb.push(testJitterBlock(5), now)
+	want := StreamStats{Received: 7, Lost: 2, Late: 1, Reordered: 1}
+	if jb.stats != want {
+		t.Fatalf("stats=%+v", jb.stats)
+	}
+}
+
+const testH264Sdp = "v=0\r\n" +
+	"o=- 0 0 IN IP4 127.0.0.1\r\n" +
+	"s=test\r\n" +
+	"t=0 0\r\n" +
+	"m=video 0 RTP/AVP 96\r\n" +
+	"a=rtpmap:96 H264/90000\r\n" +
+	"a=fmtp:96 packetization-mode=1;sprop-parameter-sets=Z00AHpWoKA9k,aO48gA==\r\n" +
+	"a=control:track1\r\n"
+
+func TestJitterBufferFragmentLost(t *testing.T) {
+	slice := []byte{0x41, 0x9a, 0x01, 0x02}
+	handler := func(conn net.Conn, req testRequest) bool {
+		switch req.Method {
+		case "DESCRIBE":
+			writeTestResponse(conn, req, 200, []string{"Content-Type: application/sdp"}, testH264Sdp)
+		case "SETUP":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234", "Transport: " + req.Header.Get("Transport")}, "")
+		case "PLAY":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234"}, "")
+			// IDR in three FU-A fragments, the middle one lost
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(96, 10, 3000, []byte{0x7c, 0x85, 1, 2}))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(96|0x80, 12, 3000, []byte{0x7c, 0x45, 5, 6}))
+			// the next frame arrives ahead of the fragment before it
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(96|0x80, 14, 6000, slice))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(96, 13, 6000, []byte{0x06, 0x05, 0x01, 0x80}))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(96|0x80, 15, 9000, slice))
+		case "TEARDOWN":
+			return false
+		default:
+			writeTestResponse(conn, req, 200, nil, "")
+		}
+		return true
+	}
+	srv := newTestServer(t, handler)
+	defer srv.Close()
+
+	cli, err := Dial(srv.Url())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	cli.RtspTimeout = 5 * time.Second
+	cli.JitterBufferDepth = 2
+
+	pkt, err := cli.ReadPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkt.IsKeyFrame || !bytes.HasSuffix(pkt.Data, slice) {
+		t.Fatalf("corrupt key frame passed on: %x", pkt.Data)
+	}
+	stats := cli.Stats()
+	if len(stats) != 1 || stats[0].Lost != 1 || stats[0].Reordered != 1 {
+		t.Fatalf("stats=%+v", stats)
+	}
+}
+
+func TestJitterBufferMarkerLost(t *testing.T) {
+	idr := []byte{0x65, 0x88, 0x01, 0x02}
+	handler := func(conn net.Conn, req testRequest) bool {
+		switch req.Method {
+		case "DESCRIBE":
+			writeTestResponse(conn, req, 200, []string{"Content-Type: application/sdp"}, testH264Sdp)
+		case "SETUP":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234", "Transport: " + req.Header.Get("Transport")}, "")
+		case "PLAY":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234"}, "")
+			// the last slice of a frame, with the marker bit, is lost
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(96, 10, 3000, []byte{0x41, 0x9a, 0x01}))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(96|0x80, 12, 6000, idr))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(96|0x80, 13, 9000, []byte{0x41, 0x9a, 0x03}))
+		case "TEARDOWN":
+			return false
+		default:
+			writeTestResponse(conn, req, 200, nil, "")
+		}
+		return true
+	}
+	srv := newTestServer(t, handler)
+	defer srv.Close()
+
+	cli, err := Dial(srv.Url())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	cli.RtspTimeout = 5 * time.Second
+
+	// the damaged frame goes, the key frame after it stays
+	pkt, err := cli.ReadPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pkt.IsKeyFrame || !bytes.HasSuffix(pkt.Data, idr) {
+		t.Fatalf("packet=%x keyframe=%v", pkt.Data, pkt.IsKeyFrame)
+	}
+}
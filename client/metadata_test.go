@@ -0,0 +1,122 @@
+package client
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/codec/klv"
+)
+
+const testMetadataSdp = "v=0\r\n" +
+	"o=- 0 0 IN IP4 127.0.0.1\r\n" +
+	"s=test\r\n" +
+	"t=0 0\r\n" +
+	"m=application 0 RTP/AVP 107\r\n" +
+	"a=rtpmap:107 vnd.onvif.metadata/90000\r\n" +
+	"a=control:track1\r\n"
+
+func TestMetadataReassemble(t *testing.T) {
+	doc1 := `<tt:MetadataStream xmlns:tt="http://www.onvif.org/ver10/schema"><tt:Event/></tt:MetadataStream>`
+	doc2 := `<tt:MetadataStream xmlns:tt="http://www.onvif.org/ver10/schema"/>`
+
+	handler := func(conn net.Conn, req testRequest) bool {
+		switch req.Method {
+		case "DESCRIBE":
+			writeTestResponse(conn, req, 200, []string{"Content-Type: application/sdp"}, testMetadataSdp)
+		case "SETUP":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234", "Transport: " + req.Header.Get("Transport")}, "")
+		case "PLAY":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234"}, "")
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(107, 0, 9000, []byte(doc1[:20])))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(107, 1, 9000, []byte(doc1[20:50])))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(107|0x80, 2, 9000, []byte(doc1[50:])))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(107|0x80, 3, 18000, []byte(doc2)))
+		case "TEARDOWN":
+			return false
+		default:
+			writeTestResponse(conn, req, 200, nil, "")
+		}
+		return true
+	}
+	srv := newTestServer(t, handler)
+	defer srv.Close()
+
+	cli, err := Dial(srv.Url())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	cli.RtspTimeout = 5 * time.Second
+
+	for i, want := range []struct {
+		doc string
+		tm  time.Duration
+	}{
+		{doc1, 0},
+		{doc2, 100 * time.Millisecond},
+	} {
+		pkt, err := cli.ReadPacket()
+		if err != nil {
+			t.Fatalf("packet#%d: %s", i, err)
+		}
+		if !pkt.IsMetadata || string(pkt.Data) != want.doc || pkt.Time != want.tm {
+			t.Fatalf("packet#%d data=%q time=%v", i, pkt.Data, pkt.Time)
+		}
+	}
+}
+
+const testKLVSdp = "v=0\r\n" +
+	"o=- 0 0 IN IP4 127.0.0.1\r\n" +
+	"s=test\r\n" +
+	"t=0 0\r\n" +
+	"m=application 0 RTP/AVP 96\r\n" +
+	"a=rtpmap:96 smpte336m/1000\r\n" +
+	"a=control:track1\r\n"
+
+func TestKLVReassemble(t *testing.T) {
+	unit := append(append([]byte{}, klv.UASLocalSetKey...), 0x03, 0x41, 0x01, 0x0d)
+
+	handler := func(conn net.Conn, req testRequest) bool {
+		switch req.Method {
+		case "DESCRIBE":
+			writeTestResponse(conn, req, 200, []string{"Content-Type: application/sdp"}, testKLVSdp)
+		case "SETUP":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234", "Transport: " + req.Header.Get("Transport")}, "")
+		case "PLAY":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234"}, "")
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(96, 0, 500, unit[:10]))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(96|0x80, 1, 500, unit[10:]))
+		case "TEARDOWN":
+			return false
+		default:
+			writeTestResponse(conn, req, 200, nil, "")
+		}
+		return true
+	}
+	srv := newTestServer(t, handler)
+	defer srv.Close()
+
+	cli, err := Dial(srv.Url())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	cli.RtspTimeout = 5 * time.Second
+
+	streams, err := cli.Streams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if streams[0].CodecData.Type() != av.KLV {
+		t.Fatalf("codec=%v", streams[0].CodecData.Type())
+	}
+	pkt, err := cli.ReadPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pkt.IsMetadata || string(pkt.Data) != string(unit) {
+		t.Fatalf("data=%x", pkt.Data)
+	}
+}
@@ -0,0 +1,186 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/codec/mjpeg"
+	"github.com/fanap-infra/rtsp/utils/bits/pio"
+)
+
+// mjpegFrame is a JPEG frame being reassembled from RFC 2435 fragments.
+type mjpegFrame struct {
+	started   bool
+	timestamp uint32
+	headerLen int
+	data      []byte
+	// tables sent in band, a frame may refer to them by Q with no data
+	qtables map[uint8][][]byte
+	// size sent in band once it differs from the codec data
+	width       int
+	height      int
+	sizeChanged bool
+}
+
+// mjpegCodecData makes the codec data of the size last sent in band, or of
+// the SDP if none was.
+func (self *Stream) mjpegCodecData() av.CodecData {
+	if self.jpeg.width > 0 && self.jpeg.height > 0 {
+		return mjpeg.NewCodecData(self.jpeg.width, self.jpeg.height)
+	}
+	return mjpeg.NewCodecData(self.Sdp.Width, self.Sdp.Height)
+}
+
+// handleMJPEGPayload depacketizes an RTP payload of RFC 2435 into a JFIF
+// frame, emitted as a key frame on the marker bit.
+func (self *Stream) handleMJPEGPayload(timestamp uint32, packet []byte, marker bool) (err error) {
+	/*
+		0                   1                   2                   3
+		0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+		+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+		| Type-specific |              Fragment Offset                  |
+		+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+		|      Type     |       Q       |     Width     |     Height    |
+		+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	*/
+	if len(packet) < 8 {
+		err = fmt.Errorf("rtp: mjpeg packet too short")
+		return
+	}
+	frame := &self.jpeg
+	offset := int(pio.U24BE(packet[1:4]))
+	typ := packet[4]
+	q := packet[5]
+	width := int(packet[6]) * 8
+	height := int(packet[7]) * 8
+	payload := packet[8:]
+
+	var dri uint16
+	if typ >= 64 && typ <= 127 {
+		/*
+			+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+			|       Restart Interval        |F|L|       Restart Count       |
+			+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+		*/
+		if len(payload) < 4 {
+			err = fmt.Errorf("rtp: mjpeg restart header too short")
+			return
+		}
+		dri = pio.U16BE(payload[0:2])
+		payload = payload[4:]
+	}
+
+	if offset == 0 {
+		if typ&0x3f > 1 {
+			err = fmt.Errorf("rtp: mjpeg type=%d unsupported", typ)
+			return
+		}
+
+		var qtables [][]byte
+		if q >= 128 {
+			/*
+				+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+				|      MBZ      |   Precision   |             Length            |
+				+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+				|                    Quantization Table Data                    |
+				|                              ...                              |
+				+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+			*/
+			if len(payload) < 4 {
+				err = fmt.Errorf("rtp: mjpeg quantization header too short")
+				return
+			}
+			precision := payload[1]
+			length := int(pio.U16BE(payload[2:4]))
+			if 4+length > len(payload) {
+				err = fmt.Errorf("rtp: mjpeg quantization table too short")
+				return
+			}
+			data := payload[4 : 4+length]
+			payload = payload[4+length:]
+
+			if length == 0 {
+				if qtables = frame.qtables[q]; qtables == nil {
+					err = fmt.Errorf("rtp: mjpeg quantization table of Q=%d missing", q)
+					return
+				}
+			} else {
+				for i := 0; len(data) > 0; i++ {
+					size := 64
+					if precision&(1<<uint(i)) != 0 {
+						size = 128
+					}
+					if size > len(data) {
+						err = fmt.Errorf("rtp: mjpeg quantization table invalid")
+						return
+					}
+					qtables = append(qtables, append([]byte(nil), data[:size]...))
+					data = data[size:]
+				}
+				if q != 255 { // 255 is never reused
+					if frame.qtables == nil {
+						frame.qtables = map[uint8][][]byte{}
+					}
+					frame.qtables[q] = qtables
+				}
+			}
+		} else {
+			lqt, cqt := mjpeg.MakeTables(int(q))
+			qtables = [][]byte{lqt, cqt}
+		}
+
+		if width == 0 {
+			width = self.Sdp.Width
+		}
+		if height == 0 {
+			height = self.Sdp.Height
+		}
+		if codec, ok := self.CodecData.(mjpeg.CodecData); ok && (codec.Width() != width || codec.Height() != height) {
+			frame.width = width
+			frame.height = height
+			if codec.Width() == 0 && codec.Height() == 0 {
+				// the SDP had no size
+				self.CodecData = self.mjpegCodecData()
+			} else {
+				// the frame of the new size is dropped, like the H264 one
+				// following a new SPS and PPS
+				frame.sizeChanged = true
+				frame.started = false
+				frame.data = nil
+				err = ErrCodecDataChange
+				return
+			}
+		}
+
+		header := mjpeg.MakeHeaders(typ, width, height, qtables, dri)
+		*frame = mjpegFrame{
+			started:   true,
+			timestamp: timestamp,
+			headerLen: len(header),
+			data:      header,
+			qtables:   frame.qtables,
+		}
+	} else if !frame.started || frame.timestamp != timestamp || offset != len(frame.data)-frame.headerLen {
+		// a fragment was lost, wait for the next frame
+		frame.started = false
+		frame.data = nil
+		return
+	}
+
+	frame.data = append(frame.data, payload...)
+
+	if marker {
+		b := frame.data
+		if len(b) < 2 || b[len(b)-2] != 0xff || b[len(b)-1] != mjpeg.EOI {
+			b = append(b, 0xff, mjpeg.EOI)
+		}
+		self.gotpkt = true
+		self.pkt.Data = b
+		self.pkt.IsKeyFrame = true
+		self.timestamp = timestamp
+		frame.started = false
+		frame.data = nil
+	}
+
+	return
+}
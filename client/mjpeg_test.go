@@ -0,0 +1,178 @@
+package client
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/codec/mjpeg"
+	"github.com/fanap-infra/rtsp/sdp"
+)
+
+const testMJPEGSdp = "v=0\r\n" +
+	"o=- 0 0 IN IP4 127.0.0.1\r\n" +
+	"s=test\r\n" +
+	"t=0 0\r\n" +
+	"m=video 0 RTP/AVP 26\r\n" +
+	"a=x-dimensions:64,48\r\n" +
+	"a=control:track1\r\n"
+
+// splitTestJPEG returns the quantization tables and the entropy coded data
+// of a baseline JPEG.
+func splitTestJPEG(t *testing.T, b []byte) (qtables []byte, scan []byte) {
+	pos := 2
+	for pos+4 <= len(b) {
+		marker := b[pos+1]
+		length := int(b[pos+2])<<8 | int(b[pos+3])
+		segment := b[pos+4 : pos+2+length]
+		switch marker {
+		case mjpeg.DQT:
+			for len(segment) >= 65 {
+				qtables = append(qtables, segment[1:65]...)
+				segment = segment[65:]
+			}
+		case mjpeg.SOS:
+			scan = b[pos+2+length : len(b)-2]
+			return
+		}
+		pos += 2 + length
+	}
+	t.Fatal("jpeg scan missing")
+	return
+}
+
+func TestMJPEGDepacketize(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 4), uint8(y * 5), uint8(x + y), 0xff})
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: 80}); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := jpeg.Decode(bytes.NewReader(buf.Bytes()))
+	qtables, scan := splitTestJPEG(t, buf.Bytes())
+
+	// type 1 (4:2:0), Q=255 with the tables in band, in three fragments
+	var payloads [][]byte
+	n := (len(scan) + 2) / 3
+	for offset := 0; offset < len(scan); offset += n {
+		end := offset + n
+		if end > len(scan) {
+			end = len(scan)
+		}
+		payload := []byte{0, byte(offset >> 16), byte(offset >> 8), byte(offset), 1, 255, 64 / 8, 48 / 8}
+		if offset == 0 {
+			payload = append(payload, 0, 0, byte(len(qtables)>>8), byte(len(qtables)))
+			payload = append(payload, qtables...)
+		}
+		payloads = append(payloads, append(payload, scan[offset:end]...))
+	}
+
+	handler := func(conn net.Conn, req testRequest) bool {
+		switch req.Method {
+		case "DESCRIBE":
+			writeTestResponse(conn, req, 200, []string{"Content-Type: application/sdp"}, testMJPEGSdp)
+		case "SETUP":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234", "Transport: " + req.Header.Get("Transport")}, "")
+		case "PLAY":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234"}, "")
+			for i, payload := range payloads {
+				pt := byte(26)
+				if i == len(payloads)-1 {
+					pt |= 0x80
+				}
+				writeTestInterleaved(conn, 0, makeTestRtpPacket(pt, uint16(i), 3000, payload))
+			}
+		case "TEARDOWN":
+			return false
+		default:
+			writeTestResponse(conn, req, 200, nil, "")
+		}
+		return true
+	}
+	srv := newTestServer(t, handler)
+	defer srv.Close()
+
+	cli, err := Dial(srv.Url())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	cli.RtspTimeout = 5 * time.Second
+
+	streams, err := cli.Streams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	codec, ok := streams[0].CodecData.(mjpeg.CodecData)
+	if !ok || codec.Type() != av.MJPEG || codec.Width() != 64 || codec.Height() != 48 {
+		t.Fatalf("codec=%v", streams[0].CodecData)
+	}
+
+	pkt, err := cli.ReadPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pkt.IsKeyFrame {
+		t.Fatal("mjpeg frame not a key frame")
+	}
+	got, err := jpeg.Decode(bytes.NewReader(pkt.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Bounds() != want.Bounds() {
+		t.Fatalf("bounds=%v", got.Bounds())
+	}
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			if got.At(x, y) != want.At(x, y) {
+				t.Fatalf("pixel %d,%d=%v want %v", x, y, got.At(x, y), want.At(x, y))
+			}
+		}
+	}
+}
+
+func TestMJPEGSizeChange(t *testing.T) {
+	cli := &Client{}
+	stream := &Stream{client: cli, Sdp: sdp.Media{Type: av.MJPEG, PayloadType: 26, Width: 64, Height: 48}}
+	cli.streams = []*Stream{stream}
+	if err := stream.makeCodecData(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 128x48 with Q=50, a single fragment
+	payload := []byte{0, 0, 0, 0, 1, 50, 128 / 8, 48 / 8, 0x00}
+	if err := stream.handleMJPEGPayload(0, payload, true); err != ErrCodecDataChange {
+		t.Fatalf("err=%v", err)
+	}
+	if stream.gotpkt {
+		t.Fatal("frame of the new size emitted with the old codec data")
+	}
+	if err := stream.handleRtpPacket(nil); err != ErrCodecDataChange {
+		t.Fatalf("err=%v", err)
+	}
+
+	newcli, err := cli.HandleCodecDataChange()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream = newcli.streams[0]
+	codec := stream.CodecData.(mjpeg.CodecData)
+	if codec.Width() != 128 || codec.Height() != 48 {
+		t.Fatalf("size=%dx%d", codec.Width(), codec.Height())
+	}
+	if err := stream.handleMJPEGPayload(3000, payload, true); err != nil {
+		t.Fatal(err)
+	}
+	if !stream.gotpkt || stream.timestamp != 3000 {
+		t.Fatal("frame of the new size not emitted")
+	}
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/packetizer"
+	"github.com/fanap-infra/rtsp/sdp"
+)
+
+// WriteHeader starts publishing streams to the server with ANNOUNCE, SETUP
+// (mode=record) and RECORD. Together with WritePacket and WriteTrailer the
+// Client implements av.Muxer, so avutil.CopyFile can push a file.
+func (self *Client) WriteHeader(streams []av.CodecData) (err error) {
+	if self.stage != 0 {
+		err = fmt.Errorf("rtsp: WriteHeader after Describe")
+		return
+	}
+	self.publishing = true
+
+	var medias []sdp.Media
+	self.streams = []*Stream{}
+	for i, codec := range streams {
+		var media sdp.Media
+		if media, err = sdp.NewMediaFromCodecData(codec, 96+i); err != nil {
+			return
+		}
+		media.Control = fmt.Sprintf("streamid=%d", i)
+		stream := &Stream{CodecData: codec, Sdp: media, client: self}
+		if stream.packetizer, err = packetizer.New(media, codec, self.MTU); err != nil {
+			return
+		}
+		self.streams = append(self.streams, stream)
+		medias = append(medias, media)
+	}
+
+	if err = self.Announce(medias); err != nil {
+		return
+	}
+	if err = self.SetupAll(); err != nil {
+		return
+	}
+	if err = self.Record(); err != nil {
+		return
+	}
+	return
+}
+
+// Announce sends the session description of the streams about to be recorded.
+func (self *Client) Announce(medias []sdp.Media) (err error) {
+	body := []byte(sdp.Marshal(sdp.Session{}, medias))
+	logRTSP.Debug(">", string(body))
+
+	var res Response
+	for i := 0; i < 2; i++ {
+		req := Request{
+			Method: "ANNOUNCE",
+			Uri:    self.requestUri,
+			Header: []string{"Content-Type: application/sdp"},
+			Body:   body,
+		}
+		if err = self.WriteRequest(req); err != nil {
+			return
+		}
+		if res, err = self.ReadResponse(); err != nil {
+			return
+		}
+		if res.StatusCode == 200 {
+			break
+		}
+	}
+	if res.StatusCode != 200 {
+		err = fmt.Errorf("rtsp: Announce failed, StatusCode=%d", res.StatusCode)
+		return
+	}
+
+	if self.stage == 0 {
+		self.stage = stageDescribeDone
+	}
+	return
+}
+
+func (self *Client) Record() (err error) {
+	req := Request{
+		Method: "RECORD",
+		Uri:    self.requestUri,
+		Header: []string{"Session: " + self.session, "Range: npt=0.000-"},
+	}
+	if err = self.WriteRequest(req); err != nil {
+		return
+	}
+	var res Response
+	if res, err = self.ReadResponse(); err != nil {
+		return
+	}
+	if res.StatusCode != 200 {
+		err = fmt.Errorf("rtsp: Record failed, StatusCode=%d", res.StatusCode)
+		return
+	}
+	self.stage = stageCodecDataDone
+
+	// nothing reads the connection while publishing, keep the server's
+	// RTCP and keepalive responses from filling up the socket
+	buffered, _ := self.brconn.Peek(self.brconn.Buffered())
+	r := io.MultiReader(bytes.NewReader(append([]byte(nil), buffered...)), self.conn.Conn)
+	go io.Copy(ioutil.Discard, r)
+	return
+}
+
+func (self *Client) WritePacket(pkt av.Packet) (err error) {
+	if !self.publishing || self.stage != stageCodecDataDone {
+		err = fmt.Errorf("rtsp: WritePacket before WriteHeader")
+		return
+	}
+	if pkt.Idx < 0 || int(pkt.Idx) >= len(self.streams) {
+		err = fmt.Errorf("rtsp: stream index=%d invalid", pkt.Idx)
+		return
+	}
+	if err = self.SendRtpKeepalive(); err != nil {
+		return
+	}
+
+	stream := self.streams[pkt.Idx]
+	var packets [][]byte
+	if packets, err = stream.packetizer.Packetize(pkt); err != nil {
+		return
+	}
+
+	self.conn.Timeout = self.RtspTimeout
+	for _, b := range packets {
+		if stream.udp != nil {
+			if stream.udp.serverRtp == nil {
+				err = fmt.Errorf("rtsp: server_port missing for stream#%d", pkt.Idx)
+				return
+			}
+			if _, err = stream.udp.rtp.WriteToUDP(b, stream.udp.serverRtp); err != nil {
+				return
+			}
+			continue
+		}
+		frame := make([]byte, 4+len(b))
+		frame[0] = '$'
+		frame[1] = byte(int(pkt.Idx) * 2)
+		binary.BigEndian.PutUint16(frame[2:4], uint16(len(b)))
+		copy(frame[4:], b)
+		if _, err = self.conn.Write(frame); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// WriteTrailer ends the recording session with TEARDOWN.
+func (self *Client) WriteTrailer() (err error) {
+	return self.Teardown()
+}
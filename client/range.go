@@ -0,0 +1,62 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+const clockRangeLayout = "20060102T150405"
+
+func formatNpt(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// NptRange returns a Range header value in normal play time relative to the
+// start of the presentation, npt=10.000-. A zero or negative end leaves the
+// range open.
+func NptRange(start, end time.Duration) string {
+	s := "npt=" + formatNpt(start) + "-"
+	if end > 0 {
+		s += formatNpt(end)
+	}
+	return s
+}
+
+func formatClock(t time.Time) string {
+	t = t.UTC()
+	s := t.Format(clockRangeLayout)
+	if ms := t.Nanosecond() / int(time.Millisecond); ms != 0 {
+		s += fmt.Sprintf(".%03d", ms)
+	}
+	return s + "Z"
+}
+
+// ClockRange returns an absolute Range header value in UTC, as used by
+// recorders to seek in a recording, clock=20261017T080000Z-. A zero end
+// leaves the range open.
+func ClockRange(start, end time.Time) string {
+	s := "clock=" + formatClock(start) + "-"
+	if !end.IsZero() {
+		s += formatClock(end)
+	}
+	return s
+}
+
+func formatSmpte(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	h := d / time.Hour
+	m := (d % time.Hour) / time.Minute
+	sec := (d % time.Minute) / time.Second
+	return fmt.Sprintf("%02d:%02d:%02d", h, m, sec)
+}
+
+// SmpteRange returns a Range header value in SMPTE time codes with whole
+// second precision, smpte=00:00:10-. Frame accurate ranges can be passed to
+// PlayRange as they are. A zero or negative end leaves the range open.
+func SmpteRange(start, end time.Duration) string {
+	s := "smpte=" + formatSmpte(start) + "-"
+	if end > 0 {
+		s += formatSmpte(end)
+	}
+	return s
+}
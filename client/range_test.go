@@ -0,0 +1,88 @@
+package client
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRangeFormat(t *testing.T) {
+	start := time.Date(2026, 10, 17, 8, 30, 0, 0, time.UTC)
+	for _, c := range []struct {
+		got, want string
+	}{
+		{NptRange(10*time.Second, 0), "npt=10.000-"},
+		{NptRange(1500*time.Millisecond, 3*time.Second), "npt=1.500-3.000"},
+		{ClockRange(start, time.Time{}), "clock=20261017T083000Z-"},
+		{ClockRange(start, start.Add(90*time.Second+250*time.Millisecond)), "clock=20261017T083000Z-20261017T083130.250Z"},
+		{SmpteRange(time.Hour+2*time.Minute+3*time.Second, 0), "smpte=01:02:03-"},
+	} {
+		if c.got != c.want {
+			t.Errorf("got %q want %q", c.got, c.want)
+		}
+	}
+}
+
+func TestPauseSeek(t *testing.T) {
+	payload := []byte{1, 2, 3, 4}
+	ranges := make(chan string, 1)
+	handler := func(conn net.Conn, req testRequest) bool {
+		switch req.Method {
+		case "DESCRIBE":
+			writeTestResponse(conn, req, 200, []string{"Content-Type: application/sdp"}, testSdp)
+		case "SETUP":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234", "Transport: " + req.Header.Get("Transport")}, "")
+		case "PLAY":
+			rng := req.Header.Get("Range")
+			writeTestResponse(conn, req, 200, []string{"Session: 1234"}, "")
+			// after the seek the timestamps go backwards
+			base := 100000
+			if rng != "" {
+				ranges <- rng
+				base = 5000
+			}
+			for i := 0; i < 3; i++ {
+				writeTestInterleaved(conn, 0, makeTestRtpPacket(0, uint16(base+i), uint32(base+i*160), payload))
+			}
+		case "TEARDOWN":
+			return false
+		default:
+			writeTestResponse(conn, req, 200, nil, "")
+		}
+		return true
+	}
+	srv := newTestServer(t, handler)
+	defer srv.Close()
+
+	cli, err := Dial(srv.Url())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	cli.RtspTimeout = 5 * time.Second
+
+	for i := 0; i < 2; i++ {
+		if _, err = cli.ReadPacket(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = cli.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	if err = cli.PlayRange(NptRange(10*time.Second, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if rng := <-ranges; rng != "npt=10.000-" {
+		t.Fatalf("range=%q", rng)
+	}
+
+	for i := 0; i < 3; i++ {
+		pkt, err := cli.ReadPacket()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := time.Duration(i) * 20 * time.Millisecond; pkt.Time != want {
+			t.Fatalf("packet#%d time=%v want %v", i, pkt.Time, want)
+		}
+	}
+}
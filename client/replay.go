@@ -0,0 +1,96 @@
+package client
+
+import (
+	"encoding/binary"
+	"strconv"
+	"time"
+
+	"github.com/fanap-infra/rtsp/utils"
+)
+
+// ReplayConfig turns on ONVIF replay (Profile G) for playing back
+// recordings, see the ONVIF Streaming Specification section 6.
+type ReplayConfig struct {
+	// Scale is the playback speed and direction, negative for reverse
+	// playback. Zero leaves the Scale header out, which is normal speed.
+	Scale float64
+	// NoRateControl asks the device to send as fast as the connection
+	// allows instead of in real time (Rate-Control: no).
+	NoRateControl bool
+	// Immediate makes PLAY on a playing session take effect at once
+	// instead of after the data already queued.
+	Immediate bool
+	// Frames limits which frames are sent: "intra", "predicted", or
+	// "intra/<ms>" for key frames at most every ms milliseconds. Empty
+	// sends all frames.
+	Frames string
+}
+
+// onvifReplayExtensionProfile identifies the RTP header extension defined by
+// ONVIF replay.
+const onvifReplayExtensionProfile = 0xabac
+
+// replayExtension is the content of the ONVIF replay RTP header extension.
+type replayExtension struct {
+	time          time.Time
+	cleanPoint    bool
+	endOfSection  bool
+	discontinuity bool
+}
+
+// replayTags returns the Require feature tags ONVIF replay adds to method.
+func (self *Client) replayTags(method string) (tags []string) {
+	if self.Replay == nil {
+		return
+	}
+	switch method {
+	case "DESCRIBE", "SETUP", "PLAY", "PAUSE":
+		tags = append(tags, "onvif-replay")
+	}
+	return
+}
+
+// replayHeaders returns the other headers ONVIF replay adds to method.
+func (self *Client) replayHeaders(method string) (headers []string) {
+	if self.Replay == nil || method != "PLAY" {
+		return
+	}
+	if self.Replay.Scale != 0 {
+		headers = append(headers, "Scale: "+strconv.FormatFloat(self.Replay.Scale, 'f', -1, 64))
+	}
+	if self.Replay.NoRateControl {
+		headers = append(headers, "Rate-Control: no")
+	}
+	if self.Replay.Immediate {
+		headers = append(headers, "Immediate: yes")
+	}
+	if self.Replay.Frames != "" {
+		headers = append(headers, "Frames: "+self.Replay.Frames)
+	}
+	return
+}
+
+func parseReplayExtension(profile uint16, data []byte) (ext replayExtension, ok bool) {
+	/*
+		0                   1                   2                   3
+		0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+		+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+		|      0xABAC                   |        length=3               |
+		+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+		|                          NTP timestamp...                     |
+		+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+		|                          NTP timestamp                        |
+		+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+		|C|E|D|mbz      |  CSeq         |        padding                |
+		+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	*/
+	if profile != onvifReplayExtensionProfile || len(data) < 12 {
+		return
+	}
+	ext.time = utils.NTPToTime(binary.BigEndian.Uint64(data[0:8]))
+	ext.cleanPoint = data[8]&0x80 != 0
+	ext.endOfSection = data[8]&0x40 != 0
+	ext.discontinuity = data[8]&0x20 != 0
+	ok = true
+	return
+}
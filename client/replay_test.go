@@ -0,0 +1,97 @@
+package client
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fanap-infra/rtsp/utils"
+)
+
+func makeTestReplayPacket(seq uint16, timestamp uint32, tm time.Time, flags byte, payload []byte) []byte {
+	ext := make([]byte, 16)
+	binary.BigEndian.PutUint16(ext[0:2], onvifReplayExtensionProfile)
+	binary.BigEndian.PutUint16(ext[2:4], 3)
+	binary.BigEndian.PutUint64(ext[4:12], utils.TimeToNTP(tm))
+	ext[12] = flags
+	b := makeTestRtpPacket(0, seq, timestamp, append(ext, payload...))
+	b[0] |= 0x10
+	return b
+}
+
+func TestReplay(t *testing.T) {
+	payload := []byte{1, 2, 3, 4}
+	start := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+	errs := make(chan error, 4)
+
+	handler := func(conn net.Conn, req testRequest) bool {
+		switch req.Method {
+		case "DESCRIBE", "SETUP", "PLAY":
+			if req.Header.Get("Require") != "onvif-replay" {
+				errs <- fmt.Errorf("%s without Require: onvif-replay", req.Method)
+			}
+		}
+		switch req.Method {
+		case "DESCRIBE":
+			writeTestResponse(conn, req, 200, []string{"Content-Type: application/sdp"}, testSdp)
+		case "SETUP":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234", "Transport: " + req.Header.Get("Transport")}, "")
+		case "PLAY":
+			if req.Header.Get("Scale") != "-2" || req.Header.Get("Rate-Control") != "no" ||
+				req.Header.Get("Immediate") != "yes" || req.Header.Get("Frames") != "intra" {
+				errs <- fmt.Errorf("replay headers missing: %v", req.Header)
+			}
+			writeTestResponse(conn, req, 200, []string{"Session: 1234"}, "")
+			writeTestInterleaved(conn, 0, makeTestReplayPacket(0, 1000, start, 0x80, payload))
+			writeTestInterleaved(conn, 0, makeTestReplayPacket(1, 1160, start.Add(20*time.Millisecond), 0x40, payload))
+			// 40 minutes later in the recording
+			writeTestInterleaved(conn, 0, makeTestReplayPacket(2, 1160+40*60*8000, start.Add(40*time.Minute), 0xa0, payload))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(0, 3, 1320+40*60*8000, payload))
+		case "TEARDOWN":
+			return false
+		default:
+			writeTestResponse(conn, req, 200, nil, "")
+		}
+		return true
+	}
+	srv := newTestServer(t, handler)
+	defer srv.Close()
+
+	cli, err := Dial(srv.Url())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	cli.RtspTimeout = 5 * time.Second
+	cli.Replay = &ReplayConfig{Scale: -2, NoRateControl: true, Immediate: true, Frames: "intra"}
+
+	want := []struct {
+		tm                  time.Time
+		clean, end, discont bool
+	}{
+		{start, true, false, false},
+		{start.Add(20 * time.Millisecond), false, true, false},
+		{start.Add(40 * time.Minute), true, false, true},
+		{start.Add(40 * time.Minute), false, false, false},
+	}
+	for i, w := range want {
+		pkt, err := cli.ReadPacket()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(pkt.Data, payload) {
+			t.Fatalf("packet#%d payload=%x", i, pkt.Data)
+		}
+		if !pkt.RecordingTime.Equal(w.tm) || pkt.CleanPoint != w.clean || pkt.EndOfSection != w.end || pkt.Discontinuity != w.discont {
+			t.Fatalf("packet#%d time=%v clean=%v end=%v discontinuity=%v", i, pkt.RecordingTime, pkt.CleanPoint, pkt.EndOfSection, pkt.Discontinuity)
+		}
+	}
+	select {
+	case err = <-errs:
+		t.Fatal(err)
+	default:
+	}
+}
@@ -0,0 +1,280 @@
+package client
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fanap-infra/rtsp/rtcp"
+	"github.com/fanap-infra/rtsp/utils"
+)
+
+// defaultRtcpInterval is the minimum RTCP interval of RFC 3550 6.2.
+const defaultRtcpInterval = 5 * time.Second
+
+const (
+	maxDropout  = 3000
+	maxMisorder = 100
+)
+
+// rtpStats keeps the reception statistics of one stream for receiver
+// reports, following RFC 3550 appendix A.1 and A.8.
+type rtpStats struct {
+	ssrc    uint32
+	started bool
+	baseSeq uint32
+	maxSeq  uint16
+	cycles  uint32
+
+	received      uint32
+	expectedPrior uint32
+	receivedPrior uint32
+
+	transit int64
+	jitter  float64
+
+	// last sender report
+	lastSR     uint32
+	lastSRTime time.Time
+	bye        bool
+}
+
+func (self *rtpStats) update(ssrc uint32, seq uint16, timestamp uint32, arrival uint32) {
+	if !self.started || ssrc != self.ssrc {
+		// the sender report may come ahead of the first packet
+		*self = rtpStats{
+			ssrc:       ssrc,
+			started:    true,
+			baseSeq:    uint32(seq),
+			maxSeq:     seq,
+			received:   1,
+			transit:    int64(int32(arrival - timestamp)),
+			lastSR:     self.lastSR,
+			lastSRTime: self.lastSRTime,
+			bye:        self.bye,
+		}
+		return
+	}
+
+	delta := seq - self.maxSeq
+	if delta < maxDropout {
+		if seq < self.maxSeq {
+			self.cycles += 1 << 16
+		}
+		self.maxSeq = seq
+	} else if delta <= 1<<16-maxMisorder {
+		// the source restarted its sequence, start counting again
+		self.baseSeq = self.cycles + uint32(seq)
+		self.maxSeq = seq
+		self.expectedPrior = 0
+		self.receivedPrior = 0
+		self.received = 0
+	}
+	self.received++
+
+	transit := int64(int32(arrival - timestamp))
+	d := transit - self.transit
+	self.transit = transit
+	if d < 0 {
+		d = -d
+	}
+	self.jitter += (float64(d) - self.jitter) / 16
+}
+
+// extendedSeq is the highest sequence number received with wraparounds.
+func (self *rtpStats) extendedSeq() uint32 {
+	return self.cycles + uint32(self.maxSeq)
+}
+
+func (self *rtpStats) report(now time.Time) (rr rtcp.ReceptionReport) {
+	rr.SSRC = self.ssrc
+	rr.HighestSequence = self.extendedSeq()
+	expected := rr.HighestSequence - self.baseSeq + 1
+	lost := int64(expected) - int64(self.received)
+	if lost > 0x7fffff {
+		lost = 0x7fffff
+	} else if lost < -0x800000 {
+		lost = -0x800000
+	}
+	rr.TotalLost = int32(lost)
+
+	expectedInterval := expected - self.expectedPrior
+	receivedInterval := self.received - self.receivedPrior
+	self.expectedPrior = expected
+	self.receivedPrior = self.received
+	if lostInterval := int64(expectedInterval) - int64(receivedInterval); expectedInterval > 0 && lostInterval > 0 {
+		rr.FractionLost = uint8(lostInterval << 8 / int64(expectedInterval))
+	}
+
+	rr.Jitter = uint32(self.jitter)
+	if !self.lastSRTime.IsZero() {
+		rr.LastSR = self.lastSR
+		rr.DelaySinceLastSR = uint32(now.Sub(self.lastSRTime) * 65536 / time.Second)
+	}
+	return
+}
+
+// rtpArrival is the arrival time of a packet in RTP timestamp units of the
+// stream, for the jitter estimate.
+func (self *Stream) rtpArrival(now time.Time) uint32 {
+	if self.client.rtcpEpoch.IsZero() {
+		self.client.rtcpEpoch = now
+	}
+	return uint32(int64(now.Sub(self.client.rtcpEpoch)) * int64(self.timeScale()) / int64(time.Second))
+}
+
+func (self *Stream) updateStats(packet []byte, now time.Time) {
+	if len(packet) < 12 {
+		return
+	}
+	seq := binary.BigEndian.Uint16(packet[2:4])
+	timestamp := binary.BigEndian.Uint32(packet[4:8])
+	ssrc := binary.BigEndian.Uint32(packet[8:12])
+	self.stats.update(ssrc, seq, timestamp, self.rtpArrival(now))
+}
+
+// senderClock is the wall clock to RTP timestamp mapping of the last sender
+// report of a stream.
+type senderClock struct {
+	ok      bool
+	ssrc    uint32
+	ntp     time.Time
+	rtpTime uint32
+}
+
+// hasWallClock reports whether a sender report maps the timestamps of the
+// current source of the stream.
+func (self *Stream) hasWallClock() bool {
+	return self.sr.ok && self.sr.ssrc == self.timeline.ssrc
+}
+
+// wallClock converts an RTP timestamp of the stream to sender wall clock
+// time. Timestamps up to half the 32-bit range away from the sender report
+// are placed correctly.
+func (self *Stream) wallClock(timestamp uint32) time.Time {
+	diff := int64(int32(timestamp - self.sr.rtpTime))
+	return self.sr.ntp.Add(time.Duration(diff * int64(time.Second) / int64(self.timeScale())))
+}
+
+// syncedTime places wallClock on the time line shared by all streams, which
+// starts at the first packet synced. Time never goes backwards within a
+// stream when sender reports correct the clock slightly.
+func (self *Client) syncedTime(stream *Stream, wallClock time.Time) (tm time.Duration) {
+	if self.syncRef.IsZero() {
+		self.syncRef = wallClock
+	}
+	if tm = wallClock.Sub(self.syncRef); tm < stream.lasttime {
+		tm = stream.lasttime
+	}
+	return
+}
+
+// handleRtcpPacket takes the sender reports, source descriptions and
+// goodbyes of a stream.
+func (self *Client) handleRtcpPacket(stream *Stream, b []byte, now time.Time) (err error) {
+	var packets []rtcp.Packet
+	if packets, err = rtcp.Unmarshal(b); err != nil {
+		return
+	}
+	for _, pkt := range packets {
+		switch pkt := pkt.(type) {
+		case *rtcp.SenderReport:
+			logRTP.Tracev("rtcp: sender report", "ssrc", pkt.SSRC, "ntp", pkt.NTPTime, "rtp", pkt.RTPTime)
+			stream.stats.lastSR = uint32(pkt.NTPTime >> 16)
+			stream.stats.lastSRTime = now
+			stream.sr = senderClock{
+				ok:      true,
+				ssrc:    pkt.SSRC,
+				ntp:     utils.NTPToTime(pkt.NTPTime),
+				rtpTime: pkt.RTPTime,
+			}
+		case *rtcp.SourceDescription:
+			for _, chunk := range pkt.Chunks {
+				if cname := chunk.CNAME(); cname != "" {
+					stream.cname = cname
+				}
+			}
+		case *rtcp.Goodbye:
+			logRTSP.Infov("rtcp: bye", "control", stream.Sdp.Control, "reason", pkt.Reason)
+			stream.stats.bye = true
+		}
+	}
+	return
+}
+
+// allStreamsBye reports whether every stream played has left the session.
+func (self *Client) allStreamsBye() bool {
+	if len(self.setupIdx) == 0 {
+		return false
+	}
+	for _, si := range self.setupIdx {
+		if !self.streams[si].stats.bye {
+			return false
+		}
+	}
+	return true
+}
+
+func (self *Client) rtcpCNAME() string {
+	host, _ := os.Hostname()
+	if host == "" {
+		host = "localhost"
+	}
+	return fmt.Sprintf("%08x@%s", self.rtcpSSRC, host)
+}
+
+// sendReceiverReports sends a receiver report for every stream that got
+// media, at most once per RtcpInterval.
+func (self *Client) sendReceiverReports() (err error) {
+	if self.RtcpInterval < 0 || self.publishing {
+		return
+	}
+	interval := self.RtcpInterval
+	if interval == 0 {
+		interval = defaultRtcpInterval
+	}
+	now := time.Now()
+	if now.Sub(self.rtcpReportTime) < interval {
+		return
+	}
+	self.rtcpReportTime = now
+	if self.rtcpSSRC == 0 {
+		var b [4]byte
+		rand.Read(b[:])
+		self.rtcpSSRC = binary.BigEndian.Uint32(b[:])
+	}
+
+	sdes := &rtcp.SourceDescription{Chunks: []rtcp.SDESChunk{{
+		Source: self.rtcpSSRC,
+		Items:  []rtcp.SDESItem{{Type: rtcp.SDESCNAME, Text: self.rtcpCNAME()}},
+	}}}
+	for _, si := range self.setupIdx {
+		stream := self.streams[si]
+		if !stream.stats.started {
+			continue
+		}
+		rr := &rtcp.ReceiverReport{
+			SSRC:    self.rtcpSSRC,
+			Reports: []rtcp.ReceptionReport{stream.stats.report(now)},
+		}
+		b := rtcp.Marshal(rr, sdes)
+
+		if stream.udp != nil {
+			if stream.udp.serverRtcp != nil {
+				stream.udp.rtcp.WriteToUDP(b, stream.udp.serverRtcp)
+			}
+			continue
+		}
+		frame := make([]byte, 4+len(b))
+		frame[0] = '$'
+		frame[1] = byte(si*2 + 1)
+		binary.BigEndian.PutUint16(frame[2:4], uint16(len(b)))
+		copy(frame[4:], b)
+		if err = self.write(frame); err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,177 @@
+package client
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fanap-infra/rtsp/rtcp"
+	"github.com/fanap-infra/rtsp/utils"
+)
+
+func TestRtpStats(t *testing.T) {
+	var stats rtpStats
+	for i, seq := range []uint16{65533, 65534, 0, 1, 3, 4} {
+		stats.update(1, seq, uint32(i*160), uint32(i*160))
+	}
+	now := time.Now()
+	stats.lastSR = 0x12345678
+	stats.lastSRTime = now.Add(-time.Second)
+
+	rr := stats.report(now)
+	if rr.HighestSequence != 1<<16+4 {
+		t.Fatalf("highest sequence=%d", rr.HighestSequence)
+	}
+	// 65535 and 2 are missing out of 8
+	if rr.TotalLost != 2 || rr.FractionLost != 64 {
+		t.Fatalf("lost=%d fraction=%d", rr.TotalLost, rr.FractionLost)
+	}
+	if rr.Jitter != 0 {
+		t.Fatalf("jitter=%d", rr.Jitter)
+	}
+	if rr.LastSR != 0x12345678 || rr.DelaySinceLastSR != 65536 {
+		t.Fatalf("lsr=%x dlsr=%d", rr.LastSR, rr.DelaySinceLastSR)
+	}
+
+	// nothing lost since the previous report
+	stats.update(1, 5, 6*160, 6*160)
+	if rr = stats.report(now); rr.FractionLost != 0 || rr.TotalLost != 2 {
+		t.Fatalf("lost=%d fraction=%d", rr.TotalLost, rr.FractionLost)
+	}
+}
+
+func TestReceiverReport(t *testing.T) {
+	payload := []byte{1, 2, 3, 4}
+	sr := &rtcp.SenderReport{SSRC: 0x12345678, NTPTime: 0xe1234567_89abcdef, RTPTime: 1000}
+	reports := make(chan *rtcp.ReceiverReport, 16)
+
+	handler := func(conn net.Conn, req testRequest) bool {
+		switch req.Method {
+		case "DESCRIBE":
+			writeTestResponse(conn, req, 200, []string{"Content-Type: application/sdp"}, testSdp)
+		case "SETUP":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234", "Transport: " + req.Header.Get("Transport")}, "")
+		case "PLAY":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234"}, "")
+			writeTestInterleaved(conn, 1, rtcp.Marshal(sr))
+			for i, seq := range []uint16{65534, 65535, 1} {
+				writeTestInterleaved(conn, 0, makeTestRtpPacket(0, seq, uint32(1000+i*160), payload))
+			}
+			time.Sleep(20 * time.Millisecond)
+			writeTestInterleaved(conn, 1, rtcp.Marshal(&rtcp.Goodbye{Sources: []uint32{sr.SSRC}}))
+		case "TEARDOWN":
+			return false
+		default:
+			writeTestResponse(conn, req, 200, nil, "")
+		}
+		return true
+	}
+	srv := newTestServer(t, handler)
+	defer srv.Close()
+	srv.onInterleaved = func(channel int, packet []byte) {
+		packets, err := rtcp.Unmarshal(packet)
+		if err != nil || channel != 1 {
+			t.Errorf("channel=%d err=%v", channel, err)
+			return
+		}
+		if rr, ok := packets[0].(*rtcp.ReceiverReport); ok {
+			reports <- rr
+		}
+	}
+
+	cli, err := Dial(srv.Url())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	cli.RtspTimeout = 5 * time.Second
+	cli.RtcpInterval = 10 * time.Millisecond
+
+	for i := 0; i < 3; i++ {
+		if _, err = cli.ReadPacket(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = cli.ReadPacket(); err != io.EOF {
+		t.Fatalf("after bye err=%v", err)
+	}
+
+	for {
+		select {
+		case rr := <-reports:
+			if len(rr.Reports) != 1 {
+				t.Fatalf("reports=%d", len(rr.Reports))
+			}
+			report := rr.Reports[0]
+			if report.HighestSequence != 1<<16+1 {
+				continue
+			}
+			if report.SSRC != 0x12345678 || report.TotalLost != 1 || report.LastSR != 0x456789ab {
+				t.Fatalf("report=%+v", report)
+			}
+			return
+		case <-time.After(5 * time.Second):
+			t.Fatal("no receiver report")
+		}
+	}
+}
+
+func TestSyncTime(t *testing.T) {
+	sdp := testSdp +
+		"m=audio 0 RTP/AVP 8\r\n" +
+		"a=rtpmap:8 PCMA/8000\r\n" +
+		"a=control:track2\r\n"
+	start := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+	payload := []byte{1, 2, 3, 4}
+
+	handler := func(conn net.Conn, req testRequest) bool {
+		switch req.Method {
+		case "DESCRIBE":
+			writeTestResponse(conn, req, 200, []string{"Content-Type: application/sdp"}, sdp)
+		case "SETUP":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234", "Transport: " + req.Header.Get("Transport")}, "")
+		case "PLAY":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234"}, "")
+			// before any sender report, dropped
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(0, 0, 840, payload))
+			writeTestInterleaved(conn, 1, rtcp.Marshal(&rtcp.SenderReport{SSRC: 0x12345678, NTPTime: utils.TimeToNTP(start), RTPTime: 1000}))
+			writeTestInterleaved(conn, 3, rtcp.Marshal(&rtcp.SenderReport{SSRC: 0x12345678, NTPTime: utils.TimeToNTP(start.Add(100 * time.Millisecond)), RTPTime: 50000}))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(0, 1, 1000, payload))
+			writeTestInterleaved(conn, 2, makeTestRtpPacket(8, 0, 50000, payload))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(0, 2, 1160, payload))
+		case "TEARDOWN":
+			return false
+		default:
+			writeTestResponse(conn, req, 200, nil, "")
+		}
+		return true
+	}
+	srv := newTestServer(t, handler)
+	defer srv.Close()
+
+	cli, err := Dial(srv.Url())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	cli.RtspTimeout = 5 * time.Second
+	cli.SyncTime = true
+
+	for i, want := range []struct {
+		idx int8
+		tm  time.Duration
+	}{
+		{0, 0},
+		{1, 100 * time.Millisecond},
+		{0, 20 * time.Millisecond},
+	} {
+		pkt, err := cli.ReadPacket()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pkt.Idx != want.idx || pkt.Time != want.tm || !pkt.WallClock.Equal(start.Add(want.tm)) {
+			t.Fatalf("packet#%d idx=%d time=%v wallclock=%v", i, pkt.Idx, pkt.Time, pkt.WallClock)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/codec/aacparser"
+	"github.com/fanap-infra/rtsp/packetizer"
 	"github.com/fanap-infra/rtsp/sdp"
 )
 
@@ -11,6 +13,10 @@ type Stream struct {
 	av.CodecData
 	Sdp    sdp.Media
 	client *Client
+	udp    *udpStream
+
+	// publishing
+	packetizer packetizer.Packetizer
 
 	// h264
 	fuStarted  bool
@@ -19,12 +25,63 @@ type Stream struct {
 	pps        []byte
 	spsChanged bool
 	ppsChanged bool
+	au         h264AccessUnit
+
+	// h265
+	vps []byte
+
+	// mjpeg
+	jpeg mjpegFrame
+
+	// aac
+	aacFrames        []aacFrame
+	aacFrag          []byte
+	aacFragSize      int
+	aacFragTimestamp uint32
+	latm             aacparser.LATMConfig
+
+	// onvif metadata and klv
+	metadata          []byte
+	metadataTimestamp uint32
 
 	gotpkt         bool
 	pkt            av.Packet
 	timestamp      uint32
-	firsttimestamp uint32
+	timeline       timeline
 	lastTimeSample uint32
 
 	lasttime time.Duration
+
+	// ONVIF replay
+	replay    replayExtension
+	gotReplay bool
+
+	// rtcp
+	stats rtpStats
+	cname string
+	sr    senderClock
+
+	jitter jitterBuffer
+}
+
+// resetTime forgets the timestamp history of the stream after a seek, the
+// first packet of the new position is time zero.
+func (self *Stream) resetTime() {
+	self.fuStarted = false
+	self.fuBuffer = nil
+	self.au = h264AccessUnit{}
+	self.jpeg = mjpegFrame{qtables: self.jpeg.qtables}
+	self.aacFrames = nil
+	self.aacFrag = nil
+	self.metadata = nil
+	self.gotpkt = false
+	self.pkt = av.Packet{}
+	self.timestamp = 0
+	self.timeline = timeline{}
+	self.lastTimeSample = 0
+	self.lasttime = 0
+	self.replay = replayExtension{}
+	self.gotReplay = false
+	self.stats.bye = false
+	self.jitter.reset()
 }
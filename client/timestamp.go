@@ -0,0 +1,91 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// DiscontinuityPolicy decides what happens to packet times when the RTP
+// timestamps of a stream jump backwards, jump further ahead than
+// maxTimestampJump, or restart with a new SSRC, as after a camera reboot.
+// The packet has av.Packet.Discontinuity set in every case but the error.
+type DiscontinuityPolicy int
+
+const (
+	// DiscontinuityRebase continues the time of the stream from the last
+	// packet, so av.Packet.Time stays monotonic.
+	DiscontinuityRebase DiscontinuityPolicy = iota
+	// DiscontinuityError fails ReadPacket.
+	DiscontinuityError
+	// DiscontinuityMark keeps following the RTP timestamps, the time may
+	// jump or go backwards.
+	DiscontinuityMark
+)
+
+func (self DiscontinuityPolicy) String() string {
+	switch self {
+	case DiscontinuityRebase:
+		return "rebase"
+	case DiscontinuityError:
+		return "error"
+	case DiscontinuityMark:
+		return "mark"
+	}
+	return fmt.Sprintf("DiscontinuityPolicy(%d)", int(self))
+}
+
+const maxTimestampJump = 30 * time.Minute
+
+// maxTimestampStepBack is how far a timestamp may go back without being a
+// discontinuity, as it does for B-frames sent in decode order.
+const maxTimestampStepBack = 5 * time.Second
+
+// timeline extends the 32-bit RTP timestamps of a stream to 64 bits, so a
+// 90 kHz stream does not wrap after 13 hours.
+type timeline struct {
+	started bool
+	ssrc    uint32
+	last    uint32
+	// extended timestamp of the last packet and of time zero
+	ext  int64
+	base int64
+}
+
+// extend returns the extended timestamp of a packet and whether it does not
+// follow on from the previous one.
+func (self *timeline) extend(timestamp uint32, ssrc uint32, clockRate int) (ext int64, discontinuity bool) {
+	if !self.started {
+		*self = timeline{started: true, ssrc: ssrc, last: timestamp}
+		return
+	}
+	diff := int64(int32(timestamp - self.last))
+	if ssrc != self.ssrc || diff < -durationToTicks(maxTimestampStepBack, clockRate) || diff > durationToTicks(maxTimestampJump, clockRate) {
+		discontinuity = true
+	}
+	self.ssrc = ssrc
+	self.last = timestamp
+	self.ext += diff
+	ext = self.ext
+	return
+}
+
+// rebase moves time zero so the extended timestamp ext is at tm.
+func (self *timeline) rebase(ext int64, tm time.Duration, clockRate int) {
+	self.base = ext - durationToTicks(tm, clockRate)
+}
+
+func (self *timeline) time(ext int64, clockRate int) time.Duration {
+	return ticksToDuration(ext-self.base, clockRate)
+}
+
+// ticksToDuration avoids overflowing int64 nanoseconds for the large
+// extended timestamps of long running streams.
+func ticksToDuration(ticks int64, clockRate int) time.Duration {
+	rate := int64(clockRate)
+	return time.Duration(ticks/rate)*time.Second + time.Duration(ticks%rate)*time.Second/time.Duration(rate)
+}
+
+func durationToTicks(tm time.Duration, clockRate int) int64 {
+	rate := int64(clockRate)
+	return int64(tm/time.Second)*rate + int64(tm%time.Second)*rate/int64(time.Second)
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTimelineWrap(t *testing.T) {
+	var tl timeline
+	ts := uint32(0xffffffff - 90000)
+	tl.extend(ts, 1, 90000)
+	for i := 0; i < 3; i++ {
+		ts += 90000
+		ext, discontinuity := tl.extend(ts, 1, 90000)
+		if discontinuity {
+			t.Fatalf("wrap taken for discontinuity at %d", i)
+		}
+		if tm := tl.time(ext, 90000); tm != time.Duration(i+1)*time.Second {
+			t.Fatalf("time=%v", tm)
+		}
+	}
+
+	// 14 hours at 90 kHz is past the 32-bit range
+	tl = timeline{}
+	tl.extend(0, 1, 90000)
+	var ext int64
+	for h := 0; h < 14*60; h++ {
+		ext, _ = tl.extend(uint32((h+1)*60*90000), 1, 90000)
+	}
+	if tm := tl.time(ext, 90000); tm != 14*time.Hour {
+		t.Fatalf("time=%v", tm)
+	}
+}
+
+func TestTimelineReorder(t *testing.T) {
+	var tl timeline
+	tl.extend(90000, 1, 90000)
+	// I P B B at 25 fps in decode order, the B-frames step back
+	for i, ts := range []uint32{90000 + 3*3600, 90000 + 3600, 90000 + 2*3600} {
+		ext, discontinuity := tl.extend(ts, 1, 90000)
+		if discontinuity {
+			t.Fatalf("frame#%d taken for discontinuity", i)
+		}
+		if ext != int64(ts)-90000 {
+			t.Fatalf("frame#%d ext=%d", i, ext)
+		}
+	}
+	// ten seconds back is a restart
+	last := uint32(90000 + 2*3600)
+	if _, discontinuity := tl.extend(last-10*90000, 1, 90000); !discontinuity {
+		t.Fatal("restart not taken for discontinuity")
+	}
+}
+
+func TestDiscontinuityPolicy(t *testing.T) {
+	payload := []byte{1, 2, 3, 4}
+	handler := func(conn net.Conn, req testRequest) bool {
+		switch req.Method {
+		case "DESCRIBE":
+			writeTestResponse(conn, req, 200, []string{"Content-Type: application/sdp"}, testSdp)
+		case "SETUP":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234", "Transport: " + req.Header.Get("Transport")}, "")
+		case "PLAY":
+			writeTestResponse(conn, req, 200, []string{"Session: 1234"}, "")
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(0, 0, 80000, payload))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(0, 1, 88000, payload))
+			// the camera restarted its clock
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(0, 2, 100, payload))
+			writeTestInterleaved(conn, 0, makeTestRtpPacket(0, 3, 260, payload))
+		case "TEARDOWN":
+			return false
+		default:
+			writeTestResponse(conn, req, 200, nil, "")
+		}
+		return true
+	}
+
+	for _, c := range []struct {
+		policy DiscontinuityPolicy
+		times  []time.Duration
+	}{
+		{DiscontinuityRebase, []time.Duration{0, time.Second, time.Second, time.Second + 20*time.Millisecond}},
+		{DiscontinuityMark, []time.Duration{0, time.Second, -9987500 * time.Microsecond, -9967500 * time.Microsecond}},
+		{DiscontinuityError, []time.Duration{0, time.Second}},
+	} {
+		srv := newTestServer(t, handler)
+		cli, err := Dial(srv.Url())
+		if err != nil {
+			t.Fatal(err)
+		}
+		cli.RtspTimeout = 5 * time.Second
+		cli.DiscontinuityPolicy = c.policy
+
+		for i, want := range c.times {
+			pkt, err := cli.ReadPacket()
+			if err != nil {
+				t.Fatalf("%v: %s", c.policy, err)
+			}
+			if pkt.Time != want || pkt.Discontinuity != (i == 2) {
+				t.Fatalf("%v: packet#%d time=%v discontinuity=%v", c.policy, i, pkt.Time, pkt.Discontinuity)
+			}
+		}
+		if c.policy == DiscontinuityError {
+			if _, err = cli.ReadPacket(); err == nil {
+				t.Fatal("discontinuity accepted")
+			}
+		}
+		cli.Close()
+		srv.Close()
+	}
+}
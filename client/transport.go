@@ -0,0 +1,100 @@
+package client
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Transport selects how RTP/RTCP is carried between the server and the client.
+type Transport int
+
+const (
+	TransportTCP          Transport = iota // RTP/AVP/TCP interleaved on the RTSP connection
+	TransportUDP                           // RTP/AVP unicast on a pair of UDP sockets per stream
+	TransportUDPMulticast                  // RTP/AVP multicast, the server chooses group and ports
+	TransportAuto                          // UDP unicast, falling back to TCP when UDP is refused or silent
+)
+
+func (self Transport) String() string {
+	switch self {
+	case TransportTCP:
+		return "tcp"
+	case TransportUDP:
+		return "udp"
+	case TransportUDPMulticast:
+		return "udp_multicast"
+	case TransportAuto:
+		return "auto"
+	}
+	return ""
+}
+
+// transportHeader is the parsed form of an RTSP Transport header (RFC 2326 12.39).
+type transportHeader struct {
+	Protocol    string
+	Multicast   bool
+	Interleaved [2]int
+	ClientPort  [2]int
+	ServerPort  [2]int
+	Port        [2]int
+	Destination string
+	Source      string
+	TTL         int
+	SSRC        string
+	Mode        string
+}
+
+func parsePortRange(val string) (ports [2]int) {
+	fields := strings.SplitN(val, "-", 2)
+	ports[0], _ = strconv.Atoi(fields[0])
+	if len(fields) == 2 {
+		ports[1], _ = strconv.Atoi(fields[1])
+	} else {
+		ports[1] = ports[0] + 1
+	}
+	return
+}
+
+func parseTransportHeader(val string) (th transportHeader) {
+	// only the first transport spec is used when the server answers with several
+	if i := strings.Index(val, ","); i >= 0 {
+		val = val[:i]
+	}
+	for i, field := range strings.Split(val, ";") {
+		field = strings.TrimSpace(field)
+		if i == 0 {
+			th.Protocol = field
+			continue
+		}
+		keyval := strings.SplitN(field, "=", 2)
+		key := strings.ToLower(keyval[0])
+		if len(keyval) == 1 {
+			switch key {
+			case "multicast":
+				th.Multicast = true
+			}
+			continue
+		}
+		switch val := strings.Trim(keyval[1], `"`); key {
+		case "interleaved":
+			th.Interleaved = parsePortRange(val)
+		case "client_port":
+			th.ClientPort = parsePortRange(val)
+		case "server_port":
+			th.ServerPort = parsePortRange(val)
+		case "port":
+			th.Port = parsePortRange(val)
+		case "destination":
+			th.Destination = val
+		case "source":
+			th.Source = val
+		case "ttl":
+			th.TTL, _ = strconv.Atoi(val)
+		case "ssrc":
+			th.SSRC = val
+		case "mode":
+			th.Mode = strings.ToLower(val)
+		}
+	}
+	return
+}
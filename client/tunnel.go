@@ -0,0 +1,130 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// httpTunnelConn is the client side of the QuickTime RTSP-over-HTTP tunnel.
+// Responses and interleaved data are read from a GET connection while
+// requests are written base64 encoded on a POST connection, the two tied
+// together by the x-sessioncookie header.
+type httpTunnelConn struct {
+	get  net.Conn
+	getr *bufio.Reader
+	post net.Conn
+}
+
+func (self *httpTunnelConn) Read(p []byte) (n int, err error) {
+	return self.getr.Read(p)
+}
+
+func (self *httpTunnelConn) Write(p []byte) (n int, err error) {
+	b := make([]byte, base64.StdEncoding.EncodedLen(len(p)))
+	base64.StdEncoding.Encode(b, p)
+	if _, err = self.post.Write(b); err != nil {
+		return
+	}
+	n = len(p)
+	return
+}
+
+func (self *httpTunnelConn) Close() error {
+	self.post.Close()
+	return self.get.Close()
+}
+
+func (self *httpTunnelConn) LocalAddr() net.Addr {
+	return self.get.LocalAddr()
+}
+
+func (self *httpTunnelConn) RemoteAddr() net.Addr {
+	return self.get.RemoteAddr()
+}
+
+func (self *httpTunnelConn) SetDeadline(t time.Time) error {
+	self.post.SetDeadline(t)
+	return self.get.SetDeadline(t)
+}
+
+func (self *httpTunnelConn) SetReadDeadline(t time.Time) error {
+	return self.get.SetReadDeadline(t)
+}
+
+func (self *httpTunnelConn) SetWriteDeadline(t time.Time) error {
+	return self.post.SetWriteDeadline(t)
+}
+
+func newSessionCookie() string {
+	b := make([]byte, 11)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func dialHTTPTunnel(ctx context.Context, dailer *net.Dialer, host string, path string) (conn net.Conn, err error) {
+	cookie := newSessionCookie()
+	if path == "" {
+		path = "/"
+	}
+
+	var get net.Conn
+	if get, err = dailer.DialContext(ctx, "tcp", host); err != nil {
+		return
+	}
+	if dailer.Timeout > 0 {
+		get.SetDeadline(time.Now().Add(dailer.Timeout))
+	}
+	fmt.Fprintf(get, "GET %s HTTP/1.0\r\n"+
+		"x-sessioncookie: %s\r\n"+
+		"Accept: application/x-rtsp-tunnelled\r\n"+
+		"Pragma: no-cache\r\n"+
+		"Cache-Control: no-cache\r\n"+
+		"\r\n", path, cookie)
+
+	getr := bufio.NewReader(get)
+	var res *http.Response
+	if res, err = http.ReadResponse(getr, nil); err != nil {
+		get.Close()
+		err = fmt.Errorf("rtsp: http tunnel: %s", err)
+		return
+	}
+	if res.StatusCode != 200 {
+		get.Close()
+		err = fmt.Errorf("rtsp: http tunnel: GET StatusCode=%d", res.StatusCode)
+		return
+	}
+	get.SetDeadline(time.Time{})
+
+	var post net.Conn
+	if post, err = dailer.DialContext(ctx, "tcp", host); err != nil {
+		get.Close()
+		return
+	}
+	// the POST body is never terminated, servers ignore the declared length
+	if _, err = fmt.Fprintf(post, "POST %s HTTP/1.0\r\n"+
+		"x-sessioncookie: %s\r\n"+
+		"Content-Type: application/x-rtsp-tunnelled\r\n"+
+		"Pragma: no-cache\r\n"+
+		"Cache-Control: no-cache\r\n"+
+		"Content-Length: 32767\r\n"+
+		"Expires: Sun, 9 Jan 1972 00:00:00 GMT\r\n"+
+		"\r\n", path, cookie); err != nil {
+		get.Close()
+		post.Close()
+		return
+	}
+
+	conn = &httpTunnelConn{
+		get:  get,
+		getr: getr,
+		post: post,
+	}
+	return
+}
@@ -0,0 +1,185 @@
+package client
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/fanap-infra/rtsp/utils"
+)
+
+const udpMaxPacketSize = 65535
+
+const defaultUDPFallbackTimeout = 3 * time.Second
+
+var errUDPTimeout = fmt.Errorf("rtp: udp read timeout")
+
+// udpStream holds the RTP/RTCP socket pair of one stream received over UDP,
+// either unicast or joined to a multicast group.
+type udpStream struct {
+	rtp  *net.UDPConn
+	rtcp *net.UDPConn
+
+	serverRtp  *net.UDPAddr
+	serverRtcp *net.UDPAddr
+
+	// multicast only
+	group *net.UDPAddr
+	ttl   int
+}
+
+type udpBlock struct {
+	block []byte
+	err   error
+}
+
+func newUDPStream() (self *udpStream, err error) {
+	self = &udpStream{}
+	if self.rtp, self.rtcp, err = utils.ListenUDPPair(); err != nil {
+		return
+	}
+	return
+}
+
+// newMulticastUDPStream joins the group announced in the SETUP response on ifi,
+// or on the system default interface when ifi is nil.
+func newMulticastUDPStream(ifi *net.Interface, th transportHeader) (self *udpStream, err error) {
+	ip := net.ParseIP(th.Destination)
+	if ip == nil || !ip.IsMulticast() {
+		err = fmt.Errorf("rtsp: multicast destination=%q invalid", th.Destination)
+		return
+	}
+	if th.Port[0] == 0 {
+		err = fmt.Errorf("rtsp: multicast port missing")
+		return
+	}
+
+	self = &udpStream{
+		group: &net.UDPAddr{IP: ip, Port: th.Port[0]},
+		ttl:   th.TTL,
+	}
+	if self.rtp, err = net.ListenMulticastUDP("udp", ifi, self.group); err != nil {
+		return
+	}
+	if self.rtcp, err = net.ListenMulticastUDP("udp", ifi, &net.UDPAddr{IP: ip, Port: th.Port[1]}); err != nil {
+		self.rtp.Close()
+		return
+	}
+	logRTSP.Debugv("rtsp: multicast joined", "group", self.group, "ttl", self.ttl)
+	return
+}
+
+func (self *udpStream) rtpPort() int {
+	return self.rtp.LocalAddr().(*net.UDPAddr).Port
+}
+
+func (self *udpStream) setServer(ip net.IP, th transportHeader) {
+	if th.ServerPort[0] > 0 {
+		self.serverRtp = &net.UDPAddr{IP: ip, Port: th.ServerPort[0]}
+		self.serverRtcp = &net.UDPAddr{IP: ip, Port: th.ServerPort[1]}
+	}
+}
+
+func (self *udpStream) close() {
+	self.rtp.Close()
+	self.rtcp.Close()
+}
+
+// serverIP returns the address that media is expected from, preferring the
+// source parameter of the Transport header over the RTSP peer address.
+func (self *Client) serverIP(th transportHeader) net.IP {
+	if th.Source != "" {
+		if ip := net.ParseIP(th.Source); ip != nil {
+			return ip
+		}
+	}
+	if addr, ok := self.conn.RemoteAddr().(*net.TCPAddr); ok {
+		return addr.IP
+	}
+	return nil
+}
+
+// readUDP frames each received datagram like an interleaved block so it can
+// go through the same handleBlock path as TCP. The channels are passed in,
+// startUDP replaces those of the client on the next start.
+func readUDP(conn *net.UDPConn, channel int, blocks chan<- udpBlock, done <-chan struct{}) {
+	buf := make([]byte, udpMaxPacketSize)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		var b udpBlock
+		if err != nil {
+			b.err = err
+		} else {
+			b.block = make([]byte, n+4)
+			b.block[0] = '$'
+			b.block[1] = byte(channel)
+			binary.BigEndian.PutUint16(b.block[2:4], uint16(n))
+			copy(b.block[4:], buf[:n])
+		}
+		select {
+		case blocks <- b:
+		case <-done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (self *Client) startUDP() {
+	self.udpBlocks = make(chan udpBlock, 256)
+	self.udpDone = make(chan struct{})
+	for _, si := range self.setupIdx {
+		if us := self.streams[si].udp; us != nil {
+			go readUDP(us.rtp, si*2, self.udpBlocks, self.udpDone)
+			go readUDP(us.rtcp, si*2+1, self.udpBlocks, self.udpDone)
+		}
+	}
+}
+
+func (self *Client) stopUDP() {
+	if self.udpDone != nil {
+		select {
+		case <-self.udpDone:
+		default:
+			close(self.udpDone)
+		}
+	}
+	for _, stream := range self.streams {
+		if stream.udp != nil {
+			stream.udp.close()
+			stream.udp = nil
+		}
+	}
+}
+
+func (self *Client) pollUDP(wait time.Duration) (block []byte, err error) {
+	var timeout <-chan time.Time
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		select {
+		case b := <-self.udpBlocks:
+			if b.err != nil {
+				err = b.err
+				return
+			}
+			if _, no, ok := self.parseBlockHeader(b.block); ok {
+				if no%2 == 0 {
+					self.udpReceived = true
+				}
+				block = b.block
+				return
+			}
+		case <-timeout:
+			err = errUDPTimeout
+			return
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package aacparser
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/fanap-infra/rtsp/utils/bits"
+)
+
+// LATMConfig is a StreamMuxConfig of ISO/IEC 14496-3 1.7.3 with a single
+// program and layer, the one MP4A-LATM streams of RFC 6416 carry.
+type LATMConfig struct {
+	AudioMuxVersion  uint
+	NumSubFrames     int
+	FrameLengthType  uint
+	FrameLength      uint // frame length type 1, payloads are FrameLength+20 bytes
+	OtherDataPresent bool
+	OtherDataLenBits uint
+	CRCCheckPresent  bool
+	Config           MPEG4AudioConfig
+}
+
+// latmGetValue reads the variable length LatmGetValue() field.
+func latmGetValue(r *bits.Reader) (value uint, err error) {
+	var bytesForValue uint
+	if bytesForValue, err = r.ReadBits(2); err != nil {
+		return
+	}
+	for i := uint(0); i <= bytesForValue; i++ {
+		var b uint
+		if b, err = r.ReadBits(8); err != nil {
+			return
+		}
+		value = value<<8 | b
+	}
+	return
+}
+
+// readAudioSpecificConfig reads an AudioSpecificConfig that is not byte
+// aligned, to its end. SBR and PS are reduced to the core object type,
+// decoders find the extensions in band.
+func readAudioSpecificConfig(r *bits.Reader) (config MPEG4AudioConfig, err error) {
+	if config.ObjectType, err = readObjectType(r); err != nil {
+		return
+	}
+	if config.SampleRateIndex, err = readSampleRateIndex(r); err != nil {
+		return
+	}
+	if config.ChannelConfig, err = r.ReadBits(4); err != nil {
+		return
+	}
+	if config.ObjectType == 5 || config.ObjectType == 29 { // SBR, PS
+		if _, err = readSampleRateIndex(r); err != nil {
+			return
+		}
+		if config.ObjectType, err = readObjectType(r); err != nil {
+			return
+		}
+	}
+
+	switch config.ObjectType {
+	case 1, 2, 3, 4, 6, 7: // GASpecificConfig
+		var dependsOnCoreCoder, extensionFlag uint
+		if _, err = r.ReadBits(1); err != nil { // frameLengthFlag
+			return
+		}
+		if dependsOnCoreCoder, err = r.ReadBits(1); err != nil {
+			return
+		}
+		if dependsOnCoreCoder != 0 {
+			if _, err = r.ReadBits(14); err != nil { // coreCoderDelay
+				return
+			}
+		}
+		if extensionFlag, err = r.ReadBits(1); err != nil {
+			return
+		}
+		if config.ChannelConfig == 0 {
+			err = fmt.Errorf("aacparser: program config element unsupported")
+			return
+		}
+		if config.ObjectType == 6 {
+			if _, err = r.ReadBits(3); err != nil { // layerNr
+				return
+			}
+		}
+		if extensionFlag != 0 {
+			if _, err = r.ReadBits(1); err != nil { // extensionFlag3
+				return
+			}
+		}
+	default:
+		err = fmt.Errorf("aacparser: latm object type=%d unsupported", config.ObjectType)
+		return
+	}
+
+	config.Complete()
+	return
+}
+
+// ParseLATMConfig parses the StreamMuxConfig of the SDP config parameter.
+func ParseLATMConfig(data []byte) (config LATMConfig, err error) {
+	r := &bits.Reader{R: bytes.NewReader(data)}
+	err = readStreamMuxConfig(r, &config)
+	return
+}
+
+func readStreamMuxConfig(r *bits.Reader, config *LATMConfig) (err error) {
+	if config.AudioMuxVersion, err = r.ReadBits(1); err != nil {
+		return
+	}
+	if config.AudioMuxVersion == 1 {
+		var versionA uint
+		if versionA, err = r.ReadBits(1); err != nil {
+			return
+		}
+		if versionA != 0 {
+			err = fmt.Errorf("aacparser: latm audioMuxVersionA=1 unsupported")
+			return
+		}
+		if _, err = latmGetValue(r); err != nil { // taraBufferFullness
+			return
+		}
+	}
+
+	var allStreamsSameTimeFraming, numSubFrames, numProgram, numLayer uint
+	if allStreamsSameTimeFraming, err = r.ReadBits(1); err != nil {
+		return
+	}
+	if numSubFrames, err = r.ReadBits(6); err != nil {
+		return
+	}
+	if numProgram, err = r.ReadBits(4); err != nil {
+		return
+	}
+	if numLayer, err = r.ReadBits(3); err != nil {
+		return
+	}
+	if allStreamsSameTimeFraming == 0 || numProgram != 0 || numLayer != 0 {
+		err = fmt.Errorf("aacparser: latm with several programs or layers unsupported")
+		return
+	}
+	config.NumSubFrames = int(numSubFrames) + 1
+
+	if config.AudioMuxVersion == 1 {
+		if _, err = latmGetValue(r); err != nil { // ascLen
+			return
+		}
+	}
+	if config.Config, err = readAudioSpecificConfig(r); err != nil {
+		return
+	}
+
+	if config.FrameLengthType, err = r.ReadBits(3); err != nil {
+		return
+	}
+	switch config.FrameLengthType {
+	case 0:
+		if _, err = r.ReadBits(8); err != nil { // latmBufferFullness
+			return
+		}
+	case 1:
+		if config.FrameLength, err = r.ReadBits(9); err != nil {
+			return
+		}
+	default:
+		err = fmt.Errorf("aacparser: latm frameLengthType=%d unsupported", config.FrameLengthType)
+		return
+	}
+
+	var flag uint
+	if flag, err = r.ReadBits(1); err != nil {
+		return
+	}
+	if config.OtherDataPresent = flag != 0; config.OtherDataPresent {
+		if config.AudioMuxVersion == 1 {
+			if config.OtherDataLenBits, err = latmGetValue(r); err != nil {
+				return
+			}
+		} else {
+			for {
+				var esc, b uint
+				if esc, err = r.ReadBits(1); err != nil {
+					return
+				}
+				if b, err = r.ReadBits(8); err != nil {
+					return
+				}
+				config.OtherDataLenBits = config.OtherDataLenBits<<8 | b
+				if esc == 0 {
+					break
+				}
+			}
+		}
+	}
+
+	if flag, err = r.ReadBits(1); err != nil {
+		return
+	}
+	if config.CRCCheckPresent = flag != 0; config.CRCCheckPresent {
+		if _, err = r.ReadBits(8); err != nil { // crcCheckSum
+			return
+		}
+	}
+	return
+}
+
+// ParseAudioMuxElement returns the raw AAC frames of an AudioMuxElement.
+// When muxConfigPresent, as with cpresent=1, the element may carry a new
+// StreamMuxConfig, which replaces config.
+func ParseAudioMuxElement(data []byte, config *LATMConfig, muxConfigPresent bool) (frames [][]byte, err error) {
+	r := &bits.Reader{R: bytes.NewReader(data)}
+	if muxConfigPresent {
+		var useSameStreamMux uint
+		if useSameStreamMux, err = r.ReadBits(1); err != nil {
+			return
+		}
+		if useSameStreamMux == 0 {
+			if err = readStreamMuxConfig(r, config); err != nil {
+				return
+			}
+		}
+	}
+	if config.NumSubFrames == 0 {
+		err = fmt.Errorf("aacparser: latm stream mux config missing")
+		return
+	}
+
+	for i := 0; i < config.NumSubFrames; i++ {
+		// PayloadLengthInfo
+		var length uint
+		switch config.FrameLengthType {
+		case 0:
+			for {
+				var b uint
+				if b, err = r.ReadBits(8); err != nil {
+					return
+				}
+				length += b
+				if b != 255 {
+					break
+				}
+			}
+		case 1:
+			length = config.FrameLength + 20
+		}
+
+		// PayloadMux, bytewise as it is not byte aligned after
+		// useSameStreamMux
+		frame := make([]byte, length)
+		for j := range frame {
+			var b uint
+			if b, err = r.ReadBits(8); err != nil {
+				err = fmt.Errorf("aacparser: latm payload too short")
+				return
+			}
+			frame[j] = byte(b)
+		}
+		frames = append(frames, frame)
+	}
+	return
+}
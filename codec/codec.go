@@ -1,12 +1,15 @@
 package codec
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/fanap-infra/rtsp/av"
 	"github.com/fanap-infra/rtsp/codec/fake"
 )
 
+// PCMUCodecData is G.711 μ-law or A-law. Packets hold one companded byte per
+// sample, SampleFormat is of the samples codec/g711 decodes them to.
 type PCMUCodecData struct {
 	typ av.CodecType
 }
@@ -62,3 +65,119 @@ func NewSpeexCodecData(sr int, cl av.ChannelLayout) SpeexCodecData {
 	codec.ChannelLayout_ = cl
 	return codec
 }
+
+// G722CodecData is G.722 at 64 kbit/s. It samples at 16 kHz but its RTP
+// clock is 8 kHz (RFC 3551 4.5.2).
+type G722CodecData struct {
+	fake.CodecData
+}
+
+func (self G722CodecData) PacketDuration(data []byte) (time.Duration, error) {
+	return time.Duration(len(data)) * time.Second / time.Duration(8000), nil
+}
+
+func NewG722CodecData() G722CodecData {
+	codec := G722CodecData{}
+	codec.CodecType_ = av.G722
+	codec.SampleFormat_ = av.S16
+	codec.SampleRate_ = 16000
+	codec.ChannelLayout_ = av.CH_MONO
+	return codec
+}
+
+// G726CodecData is G.726 ADPCM at 16, 24, 32 or 40 kbit/s. AAL2 streams
+// pack the code words from the most significant bit, RFC 3551 ones from
+// the least.
+type G726CodecData struct {
+	fake.CodecData
+	BitRate int
+	AAL2    bool
+}
+
+func (self G726CodecData) PacketDuration(data []byte) (time.Duration, error) {
+	return time.Duration(len(data)*8) * time.Second / time.Duration(self.BitRate), nil
+}
+
+func NewG726CodecData(bitRate int, aal2 bool) G726CodecData {
+	codec := G726CodecData{BitRate: bitRate, AAL2: aal2}
+	codec.CodecType_ = av.G726
+	codec.SampleFormat_ = av.S16
+	codec.SampleRate_ = 8000
+	codec.ChannelLayout_ = av.CH_MONO
+	return codec
+}
+
+// L16CodecData is interleaved 16 bit big endian PCM.
+type L16CodecData struct {
+	fake.CodecData
+}
+
+func (self L16CodecData) PacketDuration(data []byte) (time.Duration, error) {
+	samples := len(data) / (2 * self.ChannelLayout_.Count())
+	return time.Duration(samples) * time.Second / time.Duration(self.SampleRate_), nil
+}
+
+func NewL16CodecData(sr int, channels int) L16CodecData {
+	codec := L16CodecData{}
+	codec.CodecType_ = av.L16
+	codec.SampleFormat_ = av.S16
+	codec.SampleRate_ = sr
+	codec.ChannelLayout_ = channelLayout(channels)
+	return codec
+}
+
+// OpusCodecData is Opus of RFC 7587, its RTP clock is always 48 kHz.
+type OpusCodecData struct {
+	fake.CodecData
+}
+
+func (self OpusCodecData) PacketDuration(data []byte) (dur time.Duration, err error) {
+	// RFC 6716 3.1, the TOC byte
+	if len(data) < 1 {
+		err = fmt.Errorf("opus: packet too short")
+		return
+	}
+	toc := data[0]
+	config := toc >> 3
+	var frame time.Duration
+	switch {
+	case config < 12: // SILK
+		frame = []time.Duration{10, 20, 40, 60}[config%4] * time.Millisecond
+	case config < 16: // hybrid
+		frame = []time.Duration{10, 20}[config%2] * time.Millisecond
+	default: // CELT
+		frame = []time.Duration{2500, 5000, 10000, 20000}[config%4] * time.Microsecond
+	}
+	frames := 1
+	switch toc & 0x3 {
+	case 1, 2:
+		frames = 2
+	case 3:
+		if len(data) < 2 {
+			err = fmt.Errorf("opus: packet too short")
+			return
+		}
+		frames = int(data[1] & 0x3f)
+	}
+	dur = frame * time.Duration(frames)
+	return
+}
+
+func NewOpusCodecData(channels int) OpusCodecData {
+	codec := OpusCodecData{}
+	codec.CodecType_ = av.OPUS
+	codec.SampleFormat_ = av.FLT
+	codec.SampleRate_ = 48000
+	codec.ChannelLayout_ = channelLayout(channels)
+	return codec
+}
+
+func channelLayout(channels int) av.ChannelLayout {
+	switch channels {
+	case 0, 1:
+		return av.CH_MONO
+	case 2:
+		return av.CH_STEREO
+	}
+	return av.ChannelLayout(1<<uint(channels) - 1)
+}
@@ -0,0 +1,35 @@
+package codec
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fanap-infra/rtsp/av"
+)
+
+func TestPacketDuration(t *testing.T) {
+	for i, test := range []struct {
+		codec av.AudioCodecData
+		data  []byte
+		dur   time.Duration
+	}{
+		{NewPCMMulawCodecData(), make([]byte, 160), 20 * time.Millisecond},
+		{NewG722CodecData(), make([]byte, 160), 20 * time.Millisecond},
+		{NewG726CodecData(16000, false), make([]byte, 40), 20 * time.Millisecond},
+		{NewG726CodecData(40000, true), make([]byte, 100), 20 * time.Millisecond},
+		{NewL16CodecData(44100, 2), make([]byte, 4*441), 10 * time.Millisecond},
+		{NewL16CodecData(16000, 1), make([]byte, 640), 20 * time.Millisecond},
+		{NewOpusCodecData(2), []byte{0xf8, 0xff}, 20 * time.Millisecond},       // CELT 20ms, one frame
+		{NewOpusCodecData(2), []byte{0x09, 0xff}, 40 * time.Millisecond},       // SILK 20ms, two frames
+		{NewOpusCodecData(2), []byte{0xe3, 0x04, 0xff}, 10 * time.Millisecond}, // CELT 2.5ms, four frames
+		{NewOpusCodecData(1), []byte{0x18, 0x00, 0x00}, 60 * time.Millisecond}, // SILK 60ms
+	} {
+		dur, err := test.codec.PacketDuration(test.data)
+		if err != nil || dur != test.dur {
+			t.Fatalf("#%d %v duration=%v err=%v", i, test.codec.Type(), dur, err)
+		}
+	}
+	if NewL16CodecData(48000, 6).ChannelLayout().Count() != 6 {
+		t.Fatal("l16 channel count")
+	}
+}
@@ -0,0 +1,262 @@
+// Package g711 decodes and encodes G.711 μ-law and A-law audio to and from
+// 16 bit signed PCM, without cgo.
+package g711
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/av/avutil"
+	"github.com/fanap-infra/rtsp/codec"
+)
+
+const sampleRate = 8000
+
+var (
+	ulawDecodeTable [256]int16
+	alawDecodeTable [256]int16
+	// indexed by the 14 (μ-law) or 13 (A-law) most significant bits
+	ulawEncodeTable [1 << 14]byte
+	alawEncodeTable [1 << 13]byte
+)
+
+func init() {
+	for i := 0; i < 256; i++ {
+		ulawDecodeTable[i] = decodeUlaw(byte(i))
+		alawDecodeTable[i] = decodeAlaw(byte(i))
+	}
+	for i := range ulawEncodeTable {
+		ulawEncodeTable[i] = encodeUlaw(int16(uint16(i) << 2))
+	}
+	for i := range alawEncodeTable {
+		alawEncodeTable[i] = encodeAlaw(int16(uint16(i) << 3))
+	}
+}
+
+// ITU-T G.191 reference conversions, used to fill the tables.
+
+func decodeUlaw(u byte) int16 {
+	u = ^u
+	t := (int(u&0x0f) << 3) + 0x84
+	t <<= (u & 0x70) >> 4
+	if u&0x80 != 0 {
+		return int16(0x84 - t)
+	}
+	return int16(t - 0x84)
+}
+
+func decodeAlaw(a byte) int16 {
+	a ^= 0x55
+	t := int(a&0x0f) << 4
+	seg := (a & 0x70) >> 4
+	switch seg {
+	case 0:
+		t += 8
+	case 1:
+		t += 0x108
+	default:
+		t += 0x108
+		t <<= seg - 1
+	}
+	if a&0x80 != 0 {
+		return int16(t)
+	}
+	return int16(-t)
+}
+
+func segment(v int, top int) (seg int) {
+	for seg = 0; seg < 8 && v > top; seg++ {
+		top = top<<1 | 1
+	}
+	return
+}
+
+func encodeUlaw(pcm int16) byte {
+	const bias = 0x84
+	v := int(pcm) >> 2
+	mask := 0xff
+	if v < 0 {
+		v = -v
+		mask = 0x7f
+	}
+	if v > 8159 {
+		v = 8159
+	}
+	v += bias >> 2
+	seg := segment(v, 0x3f)
+	if seg >= 8 {
+		return byte(0x7f ^ mask)
+	}
+	return byte((seg<<4 | (v>>uint(seg+1))&0x0f) ^ mask)
+}
+
+func encodeAlaw(pcm int16) byte {
+	v := int(pcm) >> 3
+	mask := 0xd5
+	if v < 0 {
+		v = -v - 1
+		mask = 0x55
+	}
+	seg := segment(v, 0x1f)
+	if seg >= 8 {
+		return byte(0x7f ^ mask)
+	}
+	a := seg << 4
+	if seg < 2 {
+		a |= (v >> 1) & 0x0f
+	} else {
+		a |= (v >> uint(seg)) & 0x0f
+	}
+	return byte(a ^ mask)
+}
+
+// Decoder decodes G.711 packets into S16 frames, the samples in little
+// endian order.
+type Decoder struct {
+	codec av.AudioCodecData
+	table *[256]int16
+}
+
+func NewDecoder(codec av.AudioCodecData) (dec *Decoder, err error) {
+	dec = &Decoder{codec: codec}
+	switch codec.Type() {
+	case av.PCM_MULAW:
+		dec.table = &ulawDecodeTable
+	case av.PCM_ALAW:
+		dec.table = &alawDecodeTable
+	default:
+		err = fmt.Errorf("g711: codec type=%v unsupported", codec.Type())
+		dec = nil
+		return
+	}
+	return
+}
+
+func (self *Decoder) Decode(pkt []byte) (ok bool, frame av.AudioFrame, err error) {
+	b := make([]byte, 2*len(pkt))
+	for i, u := range pkt {
+		binary.LittleEndian.PutUint16(b[2*i:], uint16(self.table[u]))
+	}
+	frame = av.AudioFrame{
+		SampleFormat:  av.S16,
+		ChannelLayout: av.CH_MONO,
+		SampleCount:   len(pkt),
+		SampleRate:    sampleRate,
+		Data:          [][]byte{b},
+	}
+	ok = true
+	return
+}
+
+func (self *Decoder) Close() {
+}
+
+// Encoder encodes mono S16 frames at 8 kHz into G.711 packets.
+type Encoder struct {
+	typ av.CodecType
+}
+
+func NewEncoder(typ av.CodecType) (enc *Encoder, err error) {
+	switch typ {
+	case av.PCM_MULAW, av.PCM_ALAW:
+	default:
+		err = fmt.Errorf("g711: codec type=%v unsupported", typ)
+		return
+	}
+	enc = &Encoder{typ: typ}
+	return
+}
+
+func (self *Encoder) CodecData() (codecData av.AudioCodecData, err error) {
+	if self.typ == av.PCM_MULAW {
+		codecData = codec.NewPCMMulawCodecData()
+	} else {
+		codecData = codec.NewPCMAlawCodecData()
+	}
+	return
+}
+
+func (self *Encoder) Encode(frame av.AudioFrame) (pkts [][]byte, err error) {
+	if frame.SampleFormat != av.S16 || frame.ChannelLayout != av.CH_MONO || frame.SampleRate != sampleRate || len(frame.Data) != 1 {
+		err = fmt.Errorf("g711: frame format=%v layout=%v rate=%d unsupported", frame.SampleFormat, frame.ChannelLayout, frame.SampleRate)
+		return
+	}
+	data := frame.Data[0]
+	if len(data) < 2*frame.SampleCount {
+		err = fmt.Errorf("g711: frame too short")
+		return
+	}
+	pkt := make([]byte, frame.SampleCount)
+	for i := range pkt {
+		sample := uint16(binary.LittleEndian.Uint16(data[2*i:]))
+		if self.typ == av.PCM_MULAW {
+			pkt[i] = ulawEncodeTable[sample>>2]
+		} else {
+			pkt[i] = alawEncodeTable[sample>>3]
+		}
+	}
+	pkts = [][]byte{pkt}
+	return
+}
+
+func (self *Encoder) Close() {
+}
+
+func (self *Encoder) SetSampleRate(rate int) (err error) {
+	if rate != sampleRate {
+		err = fmt.Errorf("g711: sample rate=%d unsupported", rate)
+	}
+	return
+}
+
+func (self *Encoder) SetChannelLayout(layout av.ChannelLayout) (err error) {
+	if layout != av.CH_MONO {
+		err = fmt.Errorf("g711: channel layout=%v unsupported", layout)
+	}
+	return
+}
+
+func (self *Encoder) SetSampleFormat(format av.SampleFormat) (err error) {
+	if format != av.S16 {
+		err = fmt.Errorf("g711: sample format=%v unsupported", format)
+	}
+	return
+}
+
+func (self *Encoder) SetBitrate(bitrate int) (err error) {
+	if bitrate != 64000 {
+		err = fmt.Errorf("g711: bitrate=%d unsupported", bitrate)
+	}
+	return
+}
+
+func (self *Encoder) SetOption(key string, val interface{}) (err error) {
+	err = fmt.Errorf("g711: option %s unsupported", key)
+	return
+}
+
+func (self *Encoder) GetOption(key string, val interface{}) (err error) {
+	err = fmt.Errorf("g711: option %s unsupported", key)
+	return
+}
+
+func Handler(h *avutil.RegisterHandler) {
+	h.AudioDecoder = func(codec av.AudioCodecData) (av.AudioDecoder, error) {
+		dec, err := NewDecoder(codec)
+		if err != nil {
+			return nil, err
+		}
+		return dec, nil
+	}
+
+	h.AudioEncoder = func(typ av.CodecType) (av.AudioEncoder, error) {
+		enc, err := NewEncoder(typ)
+		if err != nil {
+			return nil, err
+		}
+		return enc, nil
+	}
+
+	h.CodecTypes = []av.CodecType{av.PCM_MULAW, av.PCM_ALAW}
+}
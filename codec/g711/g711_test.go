@@ -0,0 +1,82 @@
+package g711
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/av/avutil"
+	"github.com/fanap-infra/rtsp/codec"
+)
+
+func TestDecodeTables(t *testing.T) {
+	for _, test := range []struct {
+		table *[256]int16
+		code  byte
+		pcm   int16
+	}{
+		{&ulawDecodeTable, 0xff, 0},
+		{&ulawDecodeTable, 0x80, 32124},
+		{&ulawDecodeTable, 0x00, -32124},
+		{&ulawDecodeTable, 0xfe, 8},
+		{&ulawDecodeTable, 0x7e, -8},
+		{&alawDecodeTable, 0xd5, 8},
+		{&alawDecodeTable, 0x55, -8},
+		{&alawDecodeTable, 0xaa, 32256},
+		{&alawDecodeTable, 0x2a, -32256},
+	} {
+		if pcm := test.table[test.code]; pcm != test.pcm {
+			t.Fatalf("code=%02x pcm=%d want %d", test.code, pcm, test.pcm)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	handlers := &avutil.Handlers{}
+	handlers.Add(Handler)
+
+	for _, codecData := range []av.AudioCodecData{codec.NewPCMMulawCodecData(), codec.NewPCMAlawCodecData()} {
+		dec, err := handlers.NewAudioDecoder(codecData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		enc, err := handlers.NewAudioEncoder(codecData.Type())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pkt := make([]byte, 256)
+		for i := range pkt {
+			pkt[i] = byte(i)
+		}
+		ok, frame, err := dec.Decode(pkt)
+		if !ok || err != nil || frame.SampleCount != 256 || frame.SampleFormat != av.S16 || frame.SampleRate != 8000 {
+			t.Fatalf("%v decode ok=%v err=%v frame=%+v", codecData.Type(), ok, err, frame)
+		}
+		pkts, err := enc.Encode(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, code := range pkts[0] {
+			want := byte(i)
+			if codecData.Type() == av.PCM_MULAW && want == 0x7f { // negative zero
+				want = 0xff
+			}
+			if code != want {
+				t.Fatalf("%v code=%02x re-encoded to %02x", codecData.Type(), want, code)
+			}
+		}
+	}
+}
+
+func TestEncodeClip(t *testing.T) {
+	enc, _ := NewEncoder(av.PCM_MULAW)
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint16(b, uint16(32767))
+	minimum := int16(-32768)
+	binary.LittleEndian.PutUint16(b[2:], uint16(minimum))
+	pkts, err := enc.Encode(av.AudioFrame{SampleFormat: av.S16, ChannelLayout: av.CH_MONO, SampleRate: 8000, SampleCount: 2, Data: [][]byte{b}})
+	if err != nil || pkts[0][0] != 0x80 || pkts[0][1] != 0x00 {
+		t.Fatalf("pkt=%x err=%v", pkts, err)
+	}
+}
@@ -0,0 +1,471 @@
+package h265parser
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/utils/bits"
+	"github.com/fanap-infra/rtsp/utils/bits/pio"
+)
+
+// NAL unit types, ITU-T H.265 Table 7-1.
+const (
+	NALU_BLA_W_LP   = 16
+	NALU_BLA_W_RADL = 17
+	NALU_BLA_N_LP   = 18
+	NALU_IDR_W_RADL = 19
+	NALU_IDR_N_LP   = 20
+	NALU_CRA        = 21
+	NALU_VPS        = 32
+	NALU_SPS        = 33
+	NALU_PPS        = 34
+	NALU_AUD        = 35
+	NALU_SEI_PREFIX = 39
+	NALU_SEI_SUFFIX = 40
+)
+
+// RTP payload structures, RFC 7798 section 4.4.
+const (
+	NALU_AP   = 48
+	NALU_FU   = 49
+	NALU_PACI = 50
+)
+
+var StartCodeBytes = []byte{0, 0, 1}
+var AUDBytes = []byte{0, 0, 0, 1, 0x46, 0x01, 0x50, 0, 0, 0, 1} // AUD
+
+// GetNALUType returns the type of a NAL unit from its two byte header.
+func GetNALUType(nalu []byte) int {
+	return int(nalu[0]>>1) & 0x3f
+}
+
+// IsDataNALU reports whether the NAL unit is a slice of a picture (VCL).
+func IsDataNALU(nalu []byte) bool {
+	return GetNALUType(nalu) < 32
+}
+
+// IsKeyFrameNALU reports whether the NAL unit is a slice of an IRAP picture,
+// which decoding can start from.
+func IsKeyFrameNALU(nalu []byte) bool {
+	typ := GetNALUType(nalu)
+	return typ >= NALU_BLA_W_LP && typ <= 23
+}
+
+// unescapeRBSP removes the emulation prevention bytes (00 00 03).
+func unescapeRBSP(b []byte) []byte {
+	if !bytes.Contains(b, []byte{0, 0, 3}) {
+		return b
+	}
+	out := make([]byte, 0, len(b))
+	zeros := 0
+	for _, c := range b {
+		if zeros >= 2 && c == 3 {
+			zeros = 0
+			continue
+		}
+		if c == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+type SPSInfo struct {
+	ProfileSpace              uint
+	TierFlag                  uint
+	ProfileIdc                uint
+	ProfileCompatibilityFlags uint32
+	ConstraintIndicatorFlags  uint64
+	LevelIdc                  uint
+
+	MaxSubLayersMinus1 uint
+	TemporalIdNesting  uint
+
+	ChromaFormatIdc      uint
+	BitDepthLumaMinus8   uint
+	BitDepthChromaMinus8 uint
+
+	PicWidth  uint
+	PicHeight uint
+
+	CropLeft   uint
+	CropRight  uint
+	CropTop    uint
+	CropBottom uint
+
+	Width  uint
+	Height uint
+}
+
+func ParseSPS(data []byte) (self SPSInfo, err error) {
+	r := &bits.GolombBitReader{R: bytes.NewReader(unescapeRBSP(data))}
+
+	// nal unit header
+	if _, err = r.ReadBits(16); err != nil {
+		return
+	}
+
+	// sps_video_parameter_set_id
+	if _, err = r.ReadBits(4); err != nil {
+		return
+	}
+	if self.MaxSubLayersMinus1, err = r.ReadBits(3); err != nil {
+		return
+	}
+	if self.TemporalIdNesting, err = r.ReadBit(); err != nil {
+		return
+	}
+
+	// profile_tier_level
+	if self.ProfileSpace, err = r.ReadBits(2); err != nil {
+		return
+	}
+	if self.TierFlag, err = r.ReadBit(); err != nil {
+		return
+	}
+	if self.ProfileIdc, err = r.ReadBits(5); err != nil {
+		return
+	}
+	var flags uint
+	if flags, err = r.ReadBits(32); err != nil {
+		return
+	}
+	self.ProfileCompatibilityFlags = uint32(flags)
+	for i := 0; i < 2; i++ {
+		if flags, err = r.ReadBits(24); err != nil {
+			return
+		}
+		self.ConstraintIndicatorFlags = self.ConstraintIndicatorFlags<<24 | uint64(flags)
+	}
+	if self.LevelIdc, err = r.ReadBits(8); err != nil {
+		return
+	}
+
+	subLayerProfilePresent := make([]uint, self.MaxSubLayersMinus1)
+	subLayerLevelPresent := make([]uint, self.MaxSubLayersMinus1)
+	for i := range subLayerProfilePresent {
+		if subLayerProfilePresent[i], err = r.ReadBit(); err != nil {
+			return
+		}
+		if subLayerLevelPresent[i], err = r.ReadBit(); err != nil {
+			return
+		}
+	}
+	if self.MaxSubLayersMinus1 > 0 {
+		// reserved_zero_2bits
+		if _, err = r.ReadBits(int(8-self.MaxSubLayersMinus1) * 2); err != nil {
+			return
+		}
+	}
+	for i := range subLayerProfilePresent {
+		if subLayerProfilePresent[i] != 0 {
+			if _, err = r.ReadBits(88); err != nil {
+				return
+			}
+		}
+		if subLayerLevelPresent[i] != 0 {
+			if _, err = r.ReadBits(8); err != nil {
+				return
+			}
+		}
+	}
+
+	// sps_seq_parameter_set_id
+	if _, err = r.ReadExponentialGolombCode(); err != nil {
+		return
+	}
+	if self.ChromaFormatIdc, err = r.ReadExponentialGolombCode(); err != nil {
+		return
+	}
+	if self.ChromaFormatIdc == 3 {
+		// separate_colour_plane_flag
+		if _, err = r.ReadBit(); err != nil {
+			return
+		}
+	}
+	if self.PicWidth, err = r.ReadExponentialGolombCode(); err != nil {
+		return
+	}
+	if self.PicHeight, err = r.ReadExponentialGolombCode(); err != nil {
+		return
+	}
+
+	var conformanceWindow uint
+	if conformanceWindow, err = r.ReadBit(); err != nil {
+		return
+	}
+	if conformanceWindow != 0 {
+		if self.CropLeft, err = r.ReadExponentialGolombCode(); err != nil {
+			return
+		}
+		if self.CropRight, err = r.ReadExponentialGolombCode(); err != nil {
+			return
+		}
+		if self.CropTop, err = r.ReadExponentialGolombCode(); err != nil {
+			return
+		}
+		if self.CropBottom, err = r.ReadExponentialGolombCode(); err != nil {
+			return
+		}
+	}
+
+	if self.BitDepthLumaMinus8, err = r.ReadExponentialGolombCode(); err != nil {
+		return
+	}
+	if self.BitDepthChromaMinus8, err = r.ReadExponentialGolombCode(); err != nil {
+		return
+	}
+
+	// conformance window offsets are in chroma samples
+	subWidth, subHeight := uint(1), uint(1)
+	switch self.ChromaFormatIdc {
+	case 1:
+		subWidth, subHeight = 2, 2
+	case 2:
+		subWidth = 2
+	}
+	self.Width = self.PicWidth - (self.CropLeft+self.CropRight)*subWidth
+	self.Height = self.PicHeight - (self.CropTop+self.CropBottom)*subHeight
+
+	return
+}
+
+type CodecData struct {
+	Record     []byte
+	RecordInfo HVCDecoderConfRecord
+	SPSInfo    SPSInfo
+}
+
+func (self CodecData) Type() av.CodecType {
+	return av.H265
+}
+
+func (self CodecData) HVCDecoderConfRecordBytes() []byte {
+	return self.Record
+}
+
+func (self CodecData) VPS() []byte {
+	return self.RecordInfo.VPS[0]
+}
+
+func (self CodecData) SPS() []byte {
+	return self.RecordInfo.SPS[0]
+}
+
+func (self CodecData) PPS() []byte {
+	return self.RecordInfo.PPS[0]
+}
+
+func (self CodecData) Width() int {
+	return int(self.SPSInfo.Width)
+}
+
+func (self CodecData) Height() int {
+	return int(self.SPSInfo.Height)
+}
+
+func NewCodecDataFromHVCDecoderConfRecord(record []byte) (self CodecData, err error) {
+	self.Record = record
+	if _, err = (&self.RecordInfo).Unmarshal(record); err != nil {
+		return
+	}
+	if len(self.RecordInfo.VPS) == 0 {
+		err = fmt.Errorf("h265parser: no VPS found in HVCDecoderConfRecord")
+		return
+	}
+	if len(self.RecordInfo.SPS) == 0 {
+		err = fmt.Errorf("h265parser: no SPS found in HVCDecoderConfRecord")
+		return
+	}
+	if len(self.RecordInfo.PPS) == 0 {
+		err = fmt.Errorf("h265parser: no PPS found in HVCDecoderConfRecord")
+		return
+	}
+	if self.SPSInfo, err = ParseSPS(self.RecordInfo.SPS[0]); err != nil {
+		err = fmt.Errorf("h265parser: parse SPS failed(%s)", err)
+		return
+	}
+	return
+}
+
+func NewCodecDataFromVPSAndSPSAndPPS(vps, sps, pps []byte) (self CodecData, err error) {
+	if self.SPSInfo, err = ParseSPS(sps); err != nil {
+		return
+	}
+	info := self.SPSInfo
+
+	recordinfo := HVCDecoderConfRecord{}
+	recordinfo.GeneralProfileSpace = uint8(info.ProfileSpace)
+	recordinfo.GeneralTierFlag = uint8(info.TierFlag)
+	recordinfo.GeneralProfileIdc = uint8(info.ProfileIdc)
+	recordinfo.GeneralProfileCompatibilityFlags = info.ProfileCompatibilityFlags
+	recordinfo.GeneralConstraintIndicatorFlags = info.ConstraintIndicatorFlags
+	recordinfo.GeneralLevelIdc = uint8(info.LevelIdc)
+	recordinfo.ChromaFormat = uint8(info.ChromaFormatIdc)
+	recordinfo.BitDepthLumaMinus8 = uint8(info.BitDepthLumaMinus8)
+	recordinfo.BitDepthChromaMinus8 = uint8(info.BitDepthChromaMinus8)
+	recordinfo.NumTemporalLayers = uint8(info.MaxSubLayersMinus1 + 1)
+	recordinfo.TemporalIdNested = uint8(info.TemporalIdNesting)
+	recordinfo.LengthSizeMinusOne = 3
+	recordinfo.VPS = [][]byte{vps}
+	recordinfo.SPS = [][]byte{sps}
+	recordinfo.PPS = [][]byte{pps}
+
+	buf := make([]byte, recordinfo.Len())
+	recordinfo.Marshal(buf)
+
+	self.RecordInfo = recordinfo
+	self.Record = buf
+	return
+}
+
+// HVCDecoderConfRecord is the HEVCDecoderConfigurationRecord of
+// ISO/IEC 14496-15 section 8.3.3, the hvcC box payload.
+type HVCDecoderConfRecord struct {
+	GeneralProfileSpace              uint8
+	GeneralTierFlag                  uint8
+	GeneralProfileIdc                uint8
+	GeneralProfileCompatibilityFlags uint32
+	GeneralConstraintIndicatorFlags  uint64
+	GeneralLevelIdc                  uint8
+	MinSpatialSegmentationIdc        uint16
+	ParallelismType                  uint8
+	ChromaFormat                     uint8
+	BitDepthLumaMinus8               uint8
+	BitDepthChromaMinus8             uint8
+	AvgFrameRate                     uint16
+	ConstantFrameRate                uint8
+	NumTemporalLayers                uint8
+	TemporalIdNested                 uint8
+	LengthSizeMinusOne               uint8
+	VPS                              [][]byte
+	SPS                              [][]byte
+	PPS                              [][]byte
+}
+
+var ErrDecconfInvalid = fmt.Errorf("h265parser: HVCDecoderConfRecord invalid")
+
+const hvcDecoderConfRecordHeaderLength = 23
+
+func (self *HVCDecoderConfRecord) Unmarshal(b []byte) (n int, err error) {
+	if len(b) < hvcDecoderConfRecordHeaderLength {
+		err = ErrDecconfInvalid
+		return
+	}
+
+	self.GeneralProfileSpace = b[1] >> 6
+	self.GeneralTierFlag = b[1] >> 5 & 0x01
+	self.GeneralProfileIdc = b[1] & 0x1f
+	self.GeneralProfileCompatibilityFlags = pio.U32BE(b[2:])
+	self.GeneralConstraintIndicatorFlags = uint64(pio.U16BE(b[6:]))<<32 | uint64(pio.U32BE(b[8:]))
+	self.GeneralLevelIdc = b[12]
+	self.MinSpatialSegmentationIdc = pio.U16BE(b[13:]) & 0x0fff
+	self.ParallelismType = b[15] & 0x03
+	self.ChromaFormat = b[16] & 0x03
+	self.BitDepthLumaMinus8 = b[17] & 0x07
+	self.BitDepthChromaMinus8 = b[18] & 0x07
+	self.AvgFrameRate = pio.U16BE(b[19:])
+	self.ConstantFrameRate = b[21] >> 6
+	self.NumTemporalLayers = b[21] >> 3 & 0x07
+	self.TemporalIdNested = b[21] >> 2 & 0x01
+	self.LengthSizeMinusOne = b[21] & 0x03
+	arrays := int(b[22])
+	n += hvcDecoderConfRecordHeaderLength
+
+	for i := 0; i < arrays; i++ {
+		if len(b) < n+3 {
+			err = ErrDecconfInvalid
+			return
+		}
+		typ := int(b[n] & 0x3f)
+		count := int(pio.U16BE(b[n+1:]))
+		n += 3
+
+		for j := 0; j < count; j++ {
+			if len(b) < n+2 {
+				err = ErrDecconfInvalid
+				return
+			}
+			size := int(pio.U16BE(b[n:]))
+			n += 2
+
+			if len(b) < n+size {
+				err = ErrDecconfInvalid
+				return
+			}
+			nalu := b[n : n+size]
+			n += size
+
+			switch typ {
+			case NALU_VPS:
+				self.VPS = append(self.VPS, nalu)
+			case NALU_SPS:
+				self.SPS = append(self.SPS, nalu)
+			case NALU_PPS:
+				self.PPS = append(self.PPS, nalu)
+			}
+		}
+	}
+
+	return
+}
+
+func (self HVCDecoderConfRecord) arrays() (types []int, arrays [][][]byte) {
+	for i, nalus := range [][][]byte{self.VPS, self.SPS, self.PPS} {
+		if len(nalus) > 0 {
+			types = append(types, NALU_VPS+i)
+			arrays = append(arrays, nalus)
+		}
+	}
+	return
+}
+
+func (self HVCDecoderConfRecord) Len() (n int) {
+	n = hvcDecoderConfRecordHeaderLength
+	_, arrays := self.arrays()
+	for _, nalus := range arrays {
+		n += 3
+		for _, nalu := range nalus {
+			n += 2 + len(nalu)
+		}
+	}
+	return
+}
+
+func (self HVCDecoderConfRecord) Marshal(b []byte) (n int) {
+	b[0] = 1
+	b[1] = self.GeneralProfileSpace<<6 | self.GeneralTierFlag<<5 | self.GeneralProfileIdc
+	pio.PutU32BE(b[2:], self.GeneralProfileCompatibilityFlags)
+	pio.PutU16BE(b[6:], uint16(self.GeneralConstraintIndicatorFlags>>32))
+	pio.PutU32BE(b[8:], uint32(self.GeneralConstraintIndicatorFlags))
+	b[12] = self.GeneralLevelIdc
+	pio.PutU16BE(b[13:], self.MinSpatialSegmentationIdc|0xf000)
+	b[15] = self.ParallelismType | 0xfc
+	b[16] = self.ChromaFormat | 0xfc
+	b[17] = self.BitDepthLumaMinus8 | 0xf8
+	b[18] = self.BitDepthChromaMinus8 | 0xf8
+	pio.PutU16BE(b[19:], self.AvgFrameRate)
+	b[21] = self.ConstantFrameRate<<6 | self.NumTemporalLayers<<3 | self.TemporalIdNested<<2 | self.LengthSizeMinusOne
+	types, arrays := self.arrays()
+	b[22] = uint8(len(arrays))
+	n += hvcDecoderConfRecordHeaderLength
+
+	for i, nalus := range arrays {
+		// array_completeness, all parameter sets are in the record
+		b[n] = 0x80 | uint8(types[i])
+		pio.PutU16BE(b[n+1:], uint16(len(nalus)))
+		n += 3
+		for _, nalu := range nalus {
+			pio.PutU16BE(b[n:], uint16(len(nalu)))
+			n += 2
+			copy(b[n:], nalu)
+			n += len(nalu)
+		}
+	}
+
+	return
+}
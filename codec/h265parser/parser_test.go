@@ -0,0 +1,71 @@
+package h265parser
+
+import (
+	"bytes"
+	"testing"
+)
+
+var (
+	testVPS = []byte{
+		0x40, 0x01, 0x0c, 0x01, 0xff, 0xff, 0x01, 0x60, 0x00, 0x00, 0x03, 0x00,
+		0x90, 0x00, 0x00, 0x03, 0x00, 0x00, 0x03, 0x00, 0x78, 0x99, 0x98, 0x09,
+	}
+	testSPS = []byte{
+		0x42, 0x01, 0x01, 0x01, 0x60, 0x00, 0x00, 0x03, 0x00, 0x90, 0x00, 0x00,
+		0x03, 0x00, 0x00, 0x03, 0x00, 0x78, 0xa0, 0x03, 0xc0, 0x80, 0x10, 0xe5,
+		0x96, 0x66, 0x69, 0x24, 0xca, 0xe0, 0x10, 0x00, 0x00, 0x03, 0x00, 0x10,
+		0x00, 0x00, 0x03, 0x01, 0xe0, 0x80,
+	}
+	testPPS = []byte{0x44, 0x01, 0xc1, 0x72, 0xb4, 0x62, 0x40}
+)
+
+func TestParseSPS(t *testing.T) {
+	info, err := ParseSPS(testSPS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Width != 1920 || info.Height != 1080 {
+		t.Fatalf("size=%dx%d", info.Width, info.Height)
+	}
+	if info.ProfileIdc != 1 || info.LevelIdc != 120 {
+		t.Fatalf("profile=%d level=%d", info.ProfileIdc, info.LevelIdc)
+	}
+	if info.ProfileCompatibilityFlags != 0x60000000 || info.ConstraintIndicatorFlags != 0x900000000000 {
+		t.Fatalf("flags=%x constraints=%x", info.ProfileCompatibilityFlags, info.ConstraintIndicatorFlags)
+	}
+}
+
+func TestCodecData(t *testing.T) {
+	codec, err := NewCodecDataFromVPSAndSPSAndPPS(testVPS, testSPS, testPPS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := codec.HVCDecoderConfRecordBytes()
+	if record[0] != 1 || record[1] != 0x01 || record[12] != 120 || record[21]&0x03 != 3 {
+		t.Fatalf("record header=% x", record[:23])
+	}
+
+	parsed, err := NewCodecDataFromHVCDecoderConfRecord(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(parsed.VPS(), testVPS) || !bytes.Equal(parsed.SPS(), testSPS) || !bytes.Equal(parsed.PPS(), testPPS) {
+		t.Fatal("parameter sets differ after round trip")
+	}
+	if parsed.Width() != 1920 || parsed.Height() != 1080 {
+		t.Fatalf("size=%dx%d", parsed.Width(), parsed.Height())
+	}
+	if parsed.RecordInfo.GeneralConstraintIndicatorFlags != codec.RecordInfo.GeneralConstraintIndicatorFlags ||
+		parsed.RecordInfo.NumTemporalLayers != 1 || parsed.RecordInfo.TemporalIdNested != 1 {
+		t.Fatalf("record=%+v", parsed.RecordInfo)
+	}
+}
+
+func TestNALUType(t *testing.T) {
+	if GetNALUType(testVPS) != NALU_VPS || GetNALUType(testSPS) != NALU_SPS || GetNALUType(testPPS) != NALU_PPS {
+		t.Fatal("parameter set types")
+	}
+	if !IsKeyFrameNALU([]byte{NALU_IDR_W_RADL << 1, 1}) || IsKeyFrameNALU([]byte{1 << 1, 1}) {
+		t.Fatal("key frame detection")
+	}
+}
@@ -0,0 +1,130 @@
+// Package klv decodes SMPTE 336M KLV metadata, the universal and local
+// sets of MISB standards such as the ST 0601 UAS Datalink Local Set.
+package klv
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/fanap-infra/rtsp/av"
+)
+
+type CodecData struct{}
+
+func NewCodecData() CodecData {
+	return CodecData{}
+}
+
+func (self CodecData) Type() av.CodecType {
+	return av.KLV
+}
+
+// UASLocalSetKey is the universal key of the MISB ST 0601 local set.
+var UASLocalSetKey = []byte{
+	0x06, 0x0e, 0x2b, 0x34, 0x02, 0x0b, 0x01, 0x01,
+	0x0e, 0x01, 0x03, 0x01, 0x01, 0x00, 0x00, 0x00,
+}
+
+const keyLength = 16
+
+// Item is a universal set item, a 16 byte key and its value.
+type Item struct {
+	Key   []byte
+	Value []byte
+	Raw   []byte // the whole item, key and length included
+}
+
+func (self Item) IsUASLocalSet() bool {
+	return bytes.Equal(self.Key, UASLocalSetKey)
+}
+
+// Tag is a local set item.
+type Tag struct {
+	Tag   uint64
+	Value []byte
+}
+
+// ParseBERLength reads a BER short or long form length, n is its size.
+func ParseBERLength(b []byte) (length int, n int, err error) {
+	if len(b) < 1 {
+		err = fmt.Errorf("klv: ber length missing")
+		return
+	}
+	if b[0]&0x80 == 0 {
+		length = int(b[0])
+		n = 1
+		return
+	}
+	size := int(b[0] & 0x7f)
+	if size == 0 || size > 4 || 1+size > len(b) {
+		err = fmt.Errorf("klv: ber length invalid")
+		return
+	}
+	for _, c := range b[1 : 1+size] {
+		length = length<<8 | int(c)
+	}
+	n = 1 + size
+	return
+}
+
+// ParseBEROID reads a BER-OID encoded tag, n is its size.
+func ParseBEROID(b []byte) (value uint64, n int, err error) {
+	for n < len(b) && n < 9 {
+		c := b[n]
+		n++
+		value = value<<7 | uint64(c&0x7f)
+		if c&0x80 == 0 {
+			return
+		}
+	}
+	err = fmt.Errorf("klv: ber-oid invalid")
+	return
+}
+
+// Split splits a KLV unit into its universal set items.
+func Split(unit []byte) (items []Item, err error) {
+	for len(unit) > 0 {
+		if len(unit) < keyLength {
+			err = fmt.Errorf("klv: key too short")
+			return
+		}
+		var length, n int
+		if length, n, err = ParseBERLength(unit[keyLength:]); err != nil {
+			return
+		}
+		end := keyLength + n + length
+		if end > len(unit) {
+			err = fmt.Errorf("klv: value too short")
+			return
+		}
+		items = append(items, Item{
+			Key:   unit[:keyLength],
+			Value: unit[keyLength+n : end],
+			Raw:   unit[:end],
+		})
+		unit = unit[end:]
+	}
+	return
+}
+
+// ParseLocalSet splits the value of a local set into its tags.
+func ParseLocalSet(value []byte) (tags []Tag, err error) {
+	for len(value) > 0 {
+		var tag uint64
+		var length, n, m int
+		if tag, n, err = ParseBEROID(value); err != nil {
+			return
+		}
+		if length, m, err = ParseBERLength(value[n:]); err != nil {
+			return
+		}
+		end := n + m + length
+		if end > len(value) {
+			err = fmt.Errorf("klv: local set tag=%d too short", tag)
+			return
+		}
+		tags = append(tags, Tag{Tag: tag, Value: value[n+m : end]})
+		value = value[end:]
+	}
+	return
+}
@@ -0,0 +1,77 @@
+package klv
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func makeTestST0601(corrupt bool) []byte {
+	value := []byte{
+		0x02, 0x08, 0x00, 0x04, 0x59, 0xf4, 0xa6, 0xaa, 0x4a, 0xa8, // precision time stamp
+		0x03, 0x09, 'M', 'I', 'S', 'S', 'I', 'O', 'N', '0', '1', // mission id
+		0x05, 0x02, 0x71, 0xc2, // platform heading
+		0x06, 0x02, 0xfd, 0x3d, // platform pitch
+		0x0d, 0x04, 0x55, 0x95, 0xb6, 0x6d, // sensor latitude
+		0x0e, 0x04, 0x5b, 0x53, 0x60, 0xc4, // sensor longitude
+		0x0f, 0x02, 0xc2, 0x21, // sensor true altitude
+		0x41, 0x01, 0x0d, // version
+		0x01, 0x02, // checksum, filled below
+	}
+	unit := append([]byte{}, UASLocalSetKey...)
+	unit = append(unit, byte(len(value)+2))
+	unit = append(unit, value...)
+	sum := Checksum(unit)
+	if corrupt {
+		sum++
+	}
+	return append(unit, byte(sum>>8), byte(sum))
+}
+
+func TestDecodeST0601(t *testing.T) {
+	items, err := Split(makeTestST0601(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || !items[0].IsUASLocalSet() {
+		t.Fatalf("items=%v", items)
+	}
+	st, err := DecodeST0601(items[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Timestamp.Equal(time.Date(2008, 10, 24, 0, 13, 29, 913000000, time.UTC)) || st.MissionID != "MISSION01" || st.Version != 13 {
+		t.Fatalf("timestamp=%v mission=%q version=%d", st.Timestamp, st.MissionID, st.Version)
+	}
+	for _, c := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"heading", st.PlatformHeading, 159.9744},
+		{"pitch", st.PlatformPitch, -0.4315},
+		{"latitude", st.SensorLatitude, 60.1768},
+		{"longitude", st.SensorLongitude, 128.4268},
+		{"altitude", st.SensorTrueAltitude, 14190.72},
+	} {
+		if math.Abs(c.got-c.want) > 0.001 {
+			t.Errorf("%s=%v want %v", c.name, c.got, c.want)
+		}
+	}
+
+	items, err = Split(makeTestST0601(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = DecodeST0601(items[0]); err == nil {
+		t.Fatal("checksum mismatch not detected")
+	}
+}
+
+func TestParseBER(t *testing.T) {
+	if length, n, err := ParseBERLength([]byte{0x82, 0x01, 0x02}); err != nil || length != 0x102 || n != 3 {
+		t.Fatalf("length=%d n=%d err=%v", length, n, err)
+	}
+	if tag, n, err := ParseBEROID([]byte{0x81, 0x01}); err != nil || tag != 129 || n != 2 {
+		t.Fatalf("tag=%d n=%d err=%v", tag, n, err)
+	}
+}
@@ -0,0 +1,189 @@
+package klv
+
+import (
+	"fmt"
+	"time"
+)
+
+// ST 0601 tags decoded into ST0601 fields
+const (
+	TagChecksum              = 1
+	TagPrecisionTimeStamp    = 2
+	TagMissionID             = 3
+	TagPlatformTailNumber    = 4
+	TagPlatformHeading       = 5
+	TagPlatformPitch         = 6
+	TagPlatformRoll          = 7
+	TagPlatformDesignation   = 10
+	TagImageSourceSensor     = 11
+	TagImageCoordinateSystem = 12
+	TagSensorLatitude        = 13
+	TagSensorLongitude       = 14
+	TagSensorTrueAltitude    = 15
+	TagSensorHFOV            = 16
+	TagSensorVFOV            = 17
+	TagSensorRelAzimuth      = 18
+	TagSensorRelElevation    = 19
+	TagSensorRelRoll         = 20
+	TagSlantRange            = 21
+	TagTargetWidth           = 22
+	TagFrameCenterLatitude   = 23
+	TagFrameCenterLongitude  = 24
+	TagFrameCenterElevation  = 25
+	TagVersion               = 65
+)
+
+// ST0601 is a UAS Datalink Local Set. Angles are in degrees, distances and
+// altitudes in meters. Tags holds every tag as sent, the ones not decoded
+// here included.
+type ST0601 struct {
+	Timestamp             time.Time
+	MissionID             string
+	PlatformTailNumber    string
+	PlatformDesignation   string
+	ImageSourceSensor     string
+	ImageCoordinateSystem string
+	PlatformHeading       float64
+	PlatformPitch         float64
+	PlatformRoll          float64
+	SensorLatitude        float64
+	SensorLongitude       float64
+	SensorTrueAltitude    float64
+	SensorHFOV            float64
+	SensorVFOV            float64
+	SensorRelAzimuth      float64
+	SensorRelElevation    float64
+	SensorRelRoll         float64
+	SlantRange            float64
+	TargetWidth           float64
+	FrameCenterLatitude   float64
+	FrameCenterLongitude  float64
+	FrameCenterElevation  float64
+	Version               int
+	Tags                  []Tag
+}
+
+// Checksum is the ST 0601 running sum of b, bytes at even offsets in the
+// high byte.
+func Checksum(b []byte) (sum uint16) {
+	for i, c := range b {
+		sum += uint16(c) << (8 * uint((i+1)%2))
+	}
+	return
+}
+
+func unsigned(b []byte) (v uint64) {
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return
+}
+
+func signed(b []byte) int64 {
+	v := unsigned(b)
+	shift := uint(64 - 8*len(b))
+	return int64(v<<shift) >> shift
+}
+
+// mapUnsigned maps an unsigned integer of len(b) bytes on min to max.
+func mapUnsigned(b []byte, min, max float64) float64 {
+	full := float64(uint64(1)<<uint(8*len(b)) - 1)
+	return float64(unsigned(b))*(max-min)/full + min
+}
+
+// mapSigned maps a signed integer of len(b) bytes on -limit to limit.
+func mapSigned(b []byte, limit float64) float64 {
+	full := float64(uint64(1)<<uint(8*len(b)) - 2)
+	return float64(signed(b)) * 2 * limit / full
+}
+
+// DecodeST0601 decodes the item of a UAS Datalink Local Set and checks its
+// checksum, the last tag.
+func DecodeST0601(item Item) (st ST0601, err error) {
+	if !item.IsUASLocalSet() {
+		err = fmt.Errorf("klv: key %x is not of ST 0601", item.Key)
+		return
+	}
+	if st.Tags, err = ParseLocalSet(item.Value); err != nil {
+		return
+	}
+	if len(st.Tags) == 0 {
+		err = fmt.Errorf("klv: st 0601 local set empty")
+		return
+	}
+	last := st.Tags[len(st.Tags)-1]
+	if last.Tag != TagChecksum || len(last.Value) != 2 {
+		err = fmt.Errorf("klv: st 0601 checksum missing")
+		return
+	}
+	if sum := Checksum(item.Raw[:len(item.Raw)-2]); sum != uint16(unsigned(last.Value)) {
+		err = fmt.Errorf("klv: st 0601 checksum=%04x mismatch, sent %x", sum, last.Value)
+		return
+	}
+
+	for _, tag := range st.Tags {
+		b := tag.Value
+		switch tag.Tag {
+		case TagMissionID:
+			st.MissionID = string(b)
+			continue
+		case TagPlatformTailNumber:
+			st.PlatformTailNumber = string(b)
+			continue
+		case TagPlatformDesignation:
+			st.PlatformDesignation = string(b)
+			continue
+		case TagImageSourceSensor:
+			st.ImageSourceSensor = string(b)
+			continue
+		case TagImageCoordinateSystem:
+			st.ImageCoordinateSystem = string(b)
+			continue
+		}
+
+		// the rest are integers of up to 8 bytes
+		if len(b) == 0 || len(b) > 8 {
+			continue
+		}
+		switch tag.Tag {
+		case TagPrecisionTimeStamp: // microseconds since 1970
+			us := int64(unsigned(b))
+			st.Timestamp = time.Unix(us/1e6, us%1e6*1e3).UTC()
+		case TagPlatformHeading:
+			st.PlatformHeading = mapUnsigned(b, 0, 360)
+		case TagPlatformPitch:
+			st.PlatformPitch = mapSigned(b, 20)
+		case TagPlatformRoll:
+			st.PlatformRoll = mapSigned(b, 50)
+		case TagSensorLatitude:
+			st.SensorLatitude = mapSigned(b, 90)
+		case TagSensorLongitude:
+			st.SensorLongitude = mapSigned(b, 180)
+		case TagSensorTrueAltitude:
+			st.SensorTrueAltitude = mapUnsigned(b, -900, 19000)
+		case TagSensorHFOV:
+			st.SensorHFOV = mapUnsigned(b, 0, 180)
+		case TagSensorVFOV:
+			st.SensorVFOV = mapUnsigned(b, 0, 180)
+		case TagSensorRelAzimuth:
+			st.SensorRelAzimuth = mapUnsigned(b, 0, 360)
+		case TagSensorRelElevation:
+			st.SensorRelElevation = mapSigned(b, 180)
+		case TagSensorRelRoll:
+			st.SensorRelRoll = mapUnsigned(b, 0, 360)
+		case TagSlantRange:
+			st.SlantRange = mapUnsigned(b, 0, 5000000)
+		case TagTargetWidth:
+			st.TargetWidth = mapUnsigned(b, 0, 10000)
+		case TagFrameCenterLatitude:
+			st.FrameCenterLatitude = mapSigned(b, 90)
+		case TagFrameCenterLongitude:
+			st.FrameCenterLongitude = mapSigned(b, 180)
+		case TagFrameCenterElevation:
+			st.FrameCenterElevation = mapUnsigned(b, -900, 19000)
+		case TagVersion:
+			st.Version = int(unsigned(b))
+		}
+	}
+	return
+}
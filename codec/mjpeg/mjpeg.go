@@ -0,0 +1,245 @@
+// Package mjpeg rebuilds the JFIF headers an RFC 2435 RTP stream leaves out.
+package mjpeg
+
+import (
+	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/utils/bits/pio"
+)
+
+type CodecData struct {
+	width  int
+	height int
+}
+
+func NewCodecData(width, height int) CodecData {
+	return CodecData{width: width, height: height}
+}
+
+func (self CodecData) Type() av.CodecType {
+	return av.MJPEG
+}
+
+func (self CodecData) Width() int {
+	return self.width
+}
+
+func (self CodecData) Height() int {
+	return self.height
+}
+
+// JPEG markers
+const (
+	SOI  = 0xd8
+	EOI  = 0xd9
+	SOF0 = 0xc0
+	DHT  = 0xc4
+	DQT  = 0xdb
+	DRI  = 0xdd
+	SOS  = 0xda
+	APP0 = 0xe0
+)
+
+// RFC 2435 appendix A, in natural order
+var lumaQuantizer = [64]int{
+	16, 11, 10, 16, 24, 40, 51, 61,
+	12, 12, 14, 19, 26, 58, 60, 55,
+	14, 13, 16, 24, 40, 57, 69, 56,
+	14, 17, 22, 29, 51, 87, 80, 62,
+	18, 22, 37, 56, 68, 109, 103, 77,
+	24, 35, 55, 64, 81, 104, 113, 92,
+	49, 64, 78, 87, 103, 121, 120, 101,
+	72, 92, 95, 98, 112, 100, 103, 99,
+}
+
+var chromaQuantizer = [64]int{
+	17, 18, 24, 47, 99, 99, 99, 99,
+	18, 21, 26, 66, 99, 99, 99, 99,
+	24, 26, 56, 99, 99, 99, 99, 99,
+	47, 66, 99, 99, 99, 99, 99, 99,
+	99, 99, 99, 99, 99, 99, 99, 99,
+	99, 99, 99, 99, 99, 99, 99, 99,
+	99, 99, 99, 99, 99, 99, 99, 99,
+	99, 99, 99, 99, 99, 99, 99, 99,
+}
+
+// zigzag maps the order of DQT entries to natural order.
+var zigzag = [64]int{
+	0, 1, 8, 16, 9, 2, 3, 10,
+	17, 24, 32, 25, 18, 11, 4, 5,
+	12, 19, 26, 33, 40, 48, 41, 34,
+	27, 20, 13, 6, 7, 14, 21, 28,
+	35, 42, 49, 56, 57, 50, 43, 36,
+	29, 22, 15, 23, 30, 37, 44, 51,
+	58, 59, 52, 45, 38, 31, 39, 46,
+	53, 60, 61, 54, 47, 55, 62, 63,
+}
+
+// MakeTables returns the luma and chroma quantization tables, in zigzag
+// order, of the Q factors 1-99 (RFC 2435 section 4.2).
+func MakeTables(q int) (lqt, cqt []byte) {
+	factor := q
+	if factor < 1 {
+		factor = 1
+	}
+	if factor > 99 {
+		factor = 99
+	}
+	if factor < 50 {
+		q = 5000 / factor
+	} else {
+		q = 200 - factor*2
+	}
+
+	clamp := func(v int) byte {
+		if v < 1 {
+			return 1
+		}
+		if v > 255 {
+			return 255
+		}
+		return byte(v)
+	}
+	lqt = make([]byte, 64)
+	cqt = make([]byte, 64)
+	for i := 0; i < 64; i++ {
+		lqt[i] = clamp((lumaQuantizer[zigzag[i]]*q + 50) / 100)
+		cqt[i] = clamp((chromaQuantizer[zigzag[i]]*q + 50) / 100)
+	}
+	return
+}
+
+// RFC 2435 appendix B, the ITU-T T.81 annex K tables
+var lumDCCodelens = []byte{0, 1, 5, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0, 0, 0}
+var lumDCSymbols = []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+
+var lumACCodelens = []byte{0, 2, 1, 3, 3, 2, 4, 3, 5, 5, 4, 4, 0, 0, 1, 0x7d}
+var lumACSymbols = []byte{
+	0x01, 0x02, 0x03, 0x00, 0x04, 0x11, 0x05, 0x12,
+	0x21, 0x31, 0x41, 0x06, 0x13, 0x51, 0x61, 0x07,
+	0x22, 0x71, 0x14, 0x32, 0x81, 0x91, 0xa1, 0x08,
+	0x23, 0x42, 0xb1, 0xc1, 0x15, 0x52, 0xd1, 0xf0,
+	0x24, 0x33, 0x62, 0x72, 0x82, 0x09, 0x0a, 0x16,
+	0x17, 0x18, 0x19, 0x1a, 0x25, 0x26, 0x27, 0x28,
+	0x29, 0x2a, 0x34, 0x35, 0x36, 0x37, 0x38, 0x39,
+	0x3a, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48, 0x49,
+	0x4a, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58, 0x59,
+	0x5a, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69,
+	0x6a, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78, 0x79,
+	0x7a, 0x83, 0x84, 0x85, 0x86, 0x87, 0x88, 0x89,
+	0x8a, 0x92, 0x93, 0x94, 0x95, 0x96, 0x97, 0x98,
+	0x99, 0x9a, 0xa2, 0xa3, 0xa4, 0xa5, 0xa6, 0xa7,
+	0xa8, 0xa9, 0xaa, 0xb2, 0xb3, 0xb4, 0xb5, 0xb6,
+	0xb7, 0xb8, 0xb9, 0xba, 0xc2, 0xc3, 0xc4, 0xc5,
+	0xc6, 0xc7, 0xc8, 0xc9, 0xca, 0xd2, 0xd3, 0xd4,
+	0xd5, 0xd6, 0xd7, 0xd8, 0xd9, 0xda, 0xe1, 0xe2,
+	0xe3, 0xe4, 0xe5, 0xe6, 0xe7, 0xe8, 0xe9, 0xea,
+	0xf1, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8,
+	0xf9, 0xfa,
+}
+
+var chmDCCodelens = []byte{0, 3, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0}
+var chmDCSymbols = []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+
+var chmACCodelens = []byte{0, 2, 1, 2, 4, 4, 3, 4, 7, 5, 4, 4, 0, 1, 2, 0x77}
+var chmACSymbols = []byte{
+	0x00, 0x01, 0x02, 0x03, 0x11, 0x04, 0x05, 0x21,
+	0x31, 0x06, 0x12, 0x41, 0x51, 0x07, 0x61, 0x71,
+	0x13, 0x22, 0x32, 0x81, 0x08, 0x14, 0x42, 0x91,
+	0xa1, 0xb1, 0xc1, 0x09, 0x23, 0x33, 0x52, 0xf0,
+	0x15, 0x62, 0x72, 0xd1, 0x0a, 0x16, 0x24, 0x34,
+	0xe1, 0x25, 0xf1, 0x17, 0x18, 0x19, 0x1a, 0x26,
+	0x27, 0x28, 0x29, 0x2a, 0x35, 0x36, 0x37, 0x38,
+	0x39, 0x3a, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48,
+	0x49, 0x4a, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58,
+	0x59, 0x5a, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68,
+	0x69, 0x6a, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78,
+	0x79, 0x7a, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87,
+	0x88, 0x89, 0x8a, 0x92, 0x93, 0x94, 0x95, 0x96,
+	0x97, 0x98, 0x99, 0x9a, 0xa2, 0xa3, 0xa4, 0xa5,
+	0xa6, 0xa7, 0xa8, 0xa9, 0xaa, 0xb2, 0xb3, 0xb4,
+	0xb5, 0xb6, 0xb7, 0xb8, 0xb9, 0xba, 0xc2, 0xc3,
+	0xc4, 0xc5, 0xc6, 0xc7, 0xc8, 0xc9, 0xca, 0xd2,
+	0xd3, 0xd4, 0xd5, 0xd6, 0xd7, 0xd8, 0xd9, 0xda,
+	0xe2, 0xe3, 0xe4, 0xe5, 0xe6, 0xe7, 0xe8, 0xe9,
+	0xea, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8,
+	0xf9, 0xfa,
+}
+
+func putMarker(b []byte, marker byte, length int) []byte {
+	b = append(b, 0xff, marker)
+	if length > 0 {
+		b = append(b, byte(length>>8), byte(length))
+	}
+	return b
+}
+
+func putHuffmanTable(b []byte, class, id byte, codelens, symbols []byte) []byte {
+	b = putMarker(b, DHT, 3+len(codelens)+len(symbols))
+	b = append(b, class<<4|id)
+	b = append(b, codelens...)
+	return append(b, symbols...)
+}
+
+// MakeHeaders returns the JFIF headers, from SOI to SOS, of a frame of the
+// RFC 2435 type (0 is 4:2:2, 1 is 4:2:0) whose entropy coded data follows.
+// qtables holds the quantization tables in zigzag order, 128 bytes long
+// when 16 bit. dri is the restart interval, zero for none.
+func MakeHeaders(typ uint8, width, height int, qtables [][]byte, dri uint16) (b []byte) {
+	b = make([]byte, 0, 1024)
+	b = putMarker(b, SOI, 0)
+
+	b = putMarker(b, APP0, 16)
+	b = append(b, 'J', 'F', 'I', 'F', 0)
+	b = append(b, 1, 1) // version 1.01
+	b = append(b, 0)    // no density units
+	b = append(b, 0, 1, 0, 1)
+	b = append(b, 0, 0) // no thumbnail
+
+	for i, table := range qtables {
+		b = putMarker(b, DQT, 3+len(table))
+		precision := byte(0)
+		if len(table) > 64 {
+			precision = 1
+		}
+		b = append(b, precision<<4|byte(i))
+		b = append(b, table...)
+	}
+
+	if dri != 0 {
+		b = putMarker(b, DRI, 4)
+		b = append(b, 0, 0)
+		pio.PutU16BE(b[len(b)-2:], dri)
+	}
+
+	chromaTable := byte(0)
+	if len(qtables) > 1 {
+		chromaTable = 1
+	}
+	// luma sampling 2x1 for 4:2:2, 2x2 for 4:2:0
+	lumaSampling := byte(0x21)
+	if typ&0x3f == 1 {
+		lumaSampling = 0x22
+	}
+	b = putMarker(b, SOF0, 17)
+	b = append(b, 8) // precision
+	b = append(b, byte(height>>8), byte(height))
+	b = append(b, byte(width>>8), byte(width))
+	b = append(b, 3) // components
+	b = append(b, 0, lumaSampling, 0)
+	b = append(b, 1, 0x11, chromaTable)
+	b = append(b, 2, 0x11, chromaTable)
+
+	b = putHuffmanTable(b, 0, 0, lumDCCodelens, lumDCSymbols)
+	b = putHuffmanTable(b, 1, 0, lumACCodelens, lumACSymbols)
+	b = putHuffmanTable(b, 0, 1, chmDCCodelens, chmDCSymbols)
+	b = putHuffmanTable(b, 1, 1, chmACCodelens, chmACSymbols)
+
+	b = putMarker(b, SOS, 12)
+	b = append(b, 3) // components
+	b = append(b, 0, 0x00)
+	b = append(b, 1, 0x11)
+	b = append(b, 2, 0x11)
+	b = append(b, 0, 63, 0) // spectral selection, successive approximation
+
+	return
+}
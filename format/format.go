@@ -2,9 +2,11 @@ package format
 
 import (
 	"github.com/fanap-infra/rtsp/av/avutil"
+	"github.com/fanap-infra/rtsp/codec/g711"
 	"github.com/fanap-infra/rtsp/format/mp4"
 )
 
 func RegisterAll() {
 	avutil.DefaultHandlers.Add(mp4.Handler)
+	avutil.DefaultHandlers.Add(g711.Handler)
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/fanap-infra/rtsp/av"
 	"github.com/fanap-infra/rtsp/codec/aacparser"
 	"github.com/fanap-infra/rtsp/codec/h264parser"
+	"github.com/fanap-infra/rtsp/codec/h265parser"
 	"github.com/fanap-infra/rtsp/format/mp4/mp4io"
 	"io"
 	"time"
@@ -89,6 +90,11 @@ func (self *Demuxer) probe() (err error) {
 				return
 			}
 			self.streams = append(self.streams, stream)
+		} else if hvc1 := atrack.GetHVC1Conf(); hvc1 != nil {
+			if stream.CodecData, err = h265parser.NewCodecDataFromHVCDecoderConfRecord(hvc1.Data); err != nil {
+				return
+			}
+			self.streams = append(self.streams, stream)
 		} else if esds := atrack.GetElemStreamDesc(); esds != nil {
 			if stream.CodecData, err = aacparser.NewCodecDataFromMPEG4AudioConfigBytes(esds.DecConfig); err != nil {
 				return
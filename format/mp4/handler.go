@@ -6,7 +6,7 @@ import (
 	"io"
 )
 
-var CodecTypes = []av.CodecType{av.H264, av.AAC}
+var CodecTypes = []av.CodecType{av.H264, av.H265, av.AAC}
 
 func Handler(h *avutil.RegisterHandler) {
 	h.Ext = ".mp4"
@@ -0,0 +1,83 @@
+package mp4
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/codec/h265parser"
+)
+
+var (
+	testH265VPS = []byte{
+		0x40, 0x01, 0x0c, 0x01, 0xff, 0xff, 0x01, 0x60, 0x00, 0x00, 0x03, 0x00,
+		0x90, 0x00, 0x00, 0x03, 0x00, 0x00, 0x03, 0x00, 0x78, 0x99, 0x98, 0x09,
+	}
+	testH265SPS = []byte{
+		0x42, 0x01, 0x01, 0x01, 0x60, 0x00, 0x00, 0x03, 0x00, 0x90, 0x00, 0x00,
+		0x03, 0x00, 0x00, 0x03, 0x00, 0x78, 0xa0, 0x03, 0xc0, 0x80, 0x10, 0xe5,
+		0x96, 0x66, 0x69, 0x24, 0xca, 0xe0, 0x10, 0x00, 0x00, 0x03, 0x00, 0x10,
+		0x00, 0x00, 0x03, 0x01, 0xe0, 0x80,
+	}
+	testH265PPS = []byte{0x44, 0x01, 0xc1, 0x72, 0xb4, 0x62, 0x40}
+)
+
+func TestH265RoundTrip(t *testing.T) {
+	codec, err := h265parser.NewCodecDataFromVPSAndSPSAndPPS(testH265VPS, testH265SPS, testH265PPS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkts := []av.Packet{
+		{IsKeyFrame: true, Data: []byte{0, 0, 0, 3, 0x26, 0x01, 0xaa}},
+		{Time: time.Second / 25, Data: []byte{0, 0, 0, 3, 0x02, 0x01, 0xd0}},
+		{Time: 2 * time.Second / 25, Data: []byte{0, 0, 0, 3, 0x02, 0x01, 0xd1}},
+	}
+
+	f, err := ioutil.TempFile("", "h265-*.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	muxer := NewMuxer(f)
+	if err = muxer.WriteHeader([]av.CodecData{codec}); err != nil {
+		t.Fatal(err)
+	}
+	for _, pkt := range pkts {
+		if err = muxer.WritePacket(pkt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = muxer.WriteTrailer(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = f.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	demuxer := NewDemuxer(f)
+	streams, err := demuxer.Streams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(streams) != 1 || streams[0].Type() != av.H265 {
+		t.Fatalf("streams=%v", streams)
+	}
+	got := streams[0].(h265parser.CodecData)
+	if !bytes.Equal(got.HVCDecoderConfRecordBytes(), codec.HVCDecoderConfRecordBytes()) || got.Width() != 1920 {
+		t.Fatal("codec data differs")
+	}
+	for i, want := range pkts {
+		pkt, err := demuxer.ReadPacket()
+		if err != nil {
+			t.Fatalf("packet#%d: %s", i, err)
+		}
+		if !bytes.Equal(pkt.Data, want.Data) || pkt.IsKeyFrame != want.IsKeyFrame || pkt.Time != want.Time {
+			t.Fatalf("packet#%d data=%x keyframe=%v time=%v", i, pkt.Data, pkt.IsKeyFrame, pkt.Time)
+		}
+	}
+}
@@ -494,6 +494,12 @@ func (self *Track) GetAVC1Conf() (conf *AVC1Conf) {
 	return
 }
 
+func (self *Track) GetHVC1Conf() (conf *HVC1Conf) {
+	atom := FindChildren(self, HVCC)
+	conf, _ = atom.(*HVC1Conf)
+	return
+}
+
 func (self *Track) GetElemStreamDesc() (esds *ElemStreamDesc) {
 	atom := FindChildren(self, ESDS)
 	esds, _ = atom.(*ElemStreamDesc)
@@ -6,6 +6,7 @@ import (
 	"github.com/fanap-infra/rtsp/av"
 	"github.com/fanap-infra/rtsp/codec/aacparser"
 	"github.com/fanap-infra/rtsp/codec/h264parser"
+	"github.com/fanap-infra/rtsp/codec/h265parser"
 	"github.com/fanap-infra/rtsp/format/mp4/mp4io"
 	"github.com/fanap-infra/rtsp/utils/bits/pio"
 	"io"
@@ -28,7 +29,7 @@ func NewMuxer(w io.WriteSeeker) *Muxer {
 
 func (self *Muxer) newStream(codec av.CodecData) (err error) {
 	switch codec.Type() {
-	case av.H264, av.AAC:
+	case av.H264, av.H265, av.AAC:
 
 	default:
 		err = fmt.Errorf("mp4: codec type=%v is not supported", codec.Type())
@@ -79,7 +80,7 @@ func (self *Muxer) newStream(codec av.CodecData) (err error) {
 	}
 
 	switch codec.Type() {
-	case av.H264:
+	case av.H264, av.H265:
 		stream.sample.SyncSample = &mp4io.SyncSample{}
 	}
 
@@ -108,15 +109,23 @@ func (self *Stream) fillTrackAtom() (err error) {
 			ColorTableId:         -1,
 			Conf:                 &mp4io.AVC1Conf{Data: codec.AVCDecoderConfRecordBytes()},
 		}
-		self.trackAtom.Media.Handler = &mp4io.HandlerRefer{
-			SubType: [4]byte{'v', 'i', 'd', 'e'},
-			Name:    []byte("Video Media Handler"),
-		}
-		self.trackAtom.Media.Info.Video = &mp4io.VideoMediaInfo{
-			Flags: 0x000001,
+		self.fillVideoTrackAtom(width, height)
+
+	} else if self.Type() == av.H265 {
+		codec := self.CodecData.(h265parser.CodecData)
+		width, height := codec.Width(), codec.Height()
+		self.sample.SampleDesc.HVC1Desc = &mp4io.HVC1Desc{
+			DataRefIdx:           1,
+			HorizontalResolution: 72,
+			VorizontalResolution: 72,
+			Width:                int16(width),
+			Height:               int16(height),
+			FrameCount:           1,
+			Depth:                24,
+			ColorTableId:         -1,
+			Conf:                 &mp4io.HVC1Conf{Data: codec.HVCDecoderConfRecordBytes()},
 		}
-		self.trackAtom.Header.TrackWidth = float64(width)
-		self.trackAtom.Header.TrackHeight = float64(height)
+		self.fillVideoTrackAtom(width, height)
 
 	} else if self.Type() == av.AAC {
 		codec := self.CodecData.(aacparser.CodecData)
@@ -144,6 +153,18 @@ func (self *Stream) fillTrackAtom() (err error) {
 	return
 }
 
+func (self *Stream) fillVideoTrackAtom(width, height int) {
+	self.trackAtom.Media.Handler = &mp4io.HandlerRefer{
+		SubType: [4]byte{'v', 'i', 'd', 'e'},
+		Name:    []byte("Video Media Handler"),
+	}
+	self.trackAtom.Media.Info.Video = &mp4io.VideoMediaInfo{
+		Flags: 0x000001,
+	}
+	self.trackAtom.Header.TrackWidth = float64(width)
+	self.trackAtom.Header.TrackHeight = float64(height)
+}
+
 func (self *Muxer) WriteHeader(streams []av.CodecData) (err error) {
 	self.streams = []*Stream{}
 	for _, stream := range streams {
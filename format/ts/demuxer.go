@@ -6,6 +6,8 @@ import (
 	"github.com/fanap-infra/rtsp/av"
 	"github.com/fanap-infra/rtsp/codec/aacparser"
 	"github.com/fanap-infra/rtsp/codec/h264parser"
+	"github.com/fanap-infra/rtsp/codec/h265parser"
+	"github.com/fanap-infra/rtsp/codec/klv"
 	"github.com/fanap-infra/rtsp/format/ts/tsio"
 	"github.com/fanap-infra/rtsp/utils/bits/pio"
 	"io"
@@ -115,8 +117,15 @@ func (self *Demuxer) initPMT(payload []byte) (err error) {
 		switch info.StreamType {
 		case tsio.ElementaryStreamTypeH264:
 			self.streams = append(self.streams, stream)
+		case tsio.ElementaryStreamTypeH265:
+			self.streams = append(self.streams, stream)
 		case tsio.ElementaryStreamTypeAdtsAAC:
 			self.streams = append(self.streams, stream)
+		case tsio.ElementaryStreamTypeMetadata:
+			if isKLV(info.Descriptors) {
+				stream.CodecData = klv.NewCodecData()
+				self.streams = append(self.streams, stream)
+			}
 		}
 	}
 	return
@@ -200,9 +209,23 @@ func (self *Stream) addPacket(payload []byte, timedelta time.Duration) {
 	if pts != dts {
 		pkt.CompositionTime = pts - dts
 	}
+	if self.streamType == tsio.ElementaryStreamTypeMetadata {
+		pkt.IsMetadata = true
+	}
 	demuxer.pkts = append(demuxer.pkts, pkt)
 }
 
+// isKLV tells a metadata stream of KLV from its metadata descriptor.
+func isKLV(descs []tsio.Descriptor) bool {
+	for _, desc := range descs {
+		if desc.Tag == tsio.DescriptorTagMetadata && len(desc.Data) >= 7 &&
+			desc.Data[2] == 0xff && string(desc.Data[3:7]) == "KLVA" {
+			return true
+		}
+	}
+	return false
+}
+
 func (self *Stream) payloadEnd() (n int, err error) {
 	payload := self.data
 	if payload == nil {
@@ -235,6 +258,20 @@ func (self *Stream) payloadEnd() (n int, err error) {
 			payload = payload[framelen:]
 		}
 
+	case tsio.ElementaryStreamTypeMetadata:
+		// metadata access unit cells, each a KLV unit
+		for len(payload) >= tsio.MetadataAUCellHeaderLength {
+			size := int(pio.U16BE(payload[3:5]))
+			payload = payload[tsio.MetadataAUCellHeaderLength:]
+			if size > len(payload) {
+				err = fmt.Errorf("ts: metadata cell size=%d exceeds payload", size)
+				return
+			}
+			self.addPacket(payload[:size], time.Duration(0))
+			n++
+			payload = payload[size:]
+		}
+
 	case tsio.ElementaryStreamTypeH264:
 		nalus, _ := h264parser.SplitNALUs(payload)
 		var sps, pps []byte
@@ -262,6 +299,48 @@ func (self *Stream) payloadEnd() (n int, err error) {
 				return
 			}
 		}
+
+	case tsio.ElementaryStreamTypeH265:
+		// one PES is one access unit, its slices make one packet
+		nalus, _ := h264parser.SplitNALUs(payload)
+		var vps, sps, pps []byte
+		var data [][]byte
+		size := 0
+		for _, nalu := range nalus {
+			if len(nalu) < 2 {
+				continue
+			}
+			switch typ := h265parser.GetNALUType(nalu); {
+			case typ == h265parser.NALU_VPS:
+				vps = nalu
+			case typ == h265parser.NALU_SPS:
+				sps = nalu
+			case typ == h265parser.NALU_PPS:
+				pps = nalu
+			case h265parser.IsDataNALU(nalu):
+				data = append(data, nalu)
+				size += 4 + len(nalu)
+			}
+		}
+
+		if self.CodecData == nil && len(vps) > 0 && len(sps) > 0 && len(pps) > 0 {
+			if self.CodecData, err = h265parser.NewCodecDataFromVPSAndSPSAndPPS(vps, sps, pps); err != nil {
+				return
+			}
+		}
+
+		if len(data) > 0 {
+			// raw nalus to avcc
+			b := make([]byte, size)
+			pos := 0
+			for _, nalu := range data {
+				pio.PutU32BE(b[pos:], uint32(len(nalu)))
+				copy(b[pos+4:], nalu)
+				pos += 4 + len(nalu)
+			}
+			self.addPacket(b, time.Duration(0))
+			n++
+		}
 	}
 
 	return
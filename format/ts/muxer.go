@@ -5,12 +5,13 @@ import (
 	"github.com/fanap-infra/rtsp/av"
 	"github.com/fanap-infra/rtsp/codec/aacparser"
 	"github.com/fanap-infra/rtsp/codec/h264parser"
+	"github.com/fanap-infra/rtsp/codec/h265parser"
 	"github.com/fanap-infra/rtsp/format/ts/tsio"
 	"io"
 	"time"
 )
 
-var CodecTypes = []av.CodecType{av.H264, av.AAC}
+var CodecTypes = []av.CodecType{av.H264, av.H265, av.AAC, av.KLV}
 
 type Muxer struct {
 	w                        io.Writer
@@ -21,6 +22,7 @@ type Muxer struct {
 	peshdr  []byte
 	tshdr   []byte
 	adtshdr []byte
+	cellhdr []byte
 	datav   [][]byte
 	nalus   [][]byte
 
@@ -34,6 +36,7 @@ func NewMuxer(w io.Writer) *Muxer {
 		peshdr:  make([]byte, tsio.MaxPESHeaderLength),
 		tshdr:   make([]byte, tsio.MaxTSHeaderLength),
 		adtshdr: make([]byte, aacparser.ADTSHeaderLength),
+		cellhdr: make([]byte, tsio.MetadataAUCellHeaderLength),
 		nalus:   make([][]byte, 16),
 		datav:   make([][]byte, 16),
 		tswpmt:  tsio.NewTSWriter(tsio.PMT_PID),
@@ -116,6 +119,29 @@ func (self *Muxer) WritePATPMT() (err error) {
 				StreamType:    tsio.ElementaryStreamTypeH264,
 				ElementaryPID: stream.pid,
 			})
+		case av.H265:
+			elemStreams = append(elemStreams, tsio.ElementaryStreamInfo{
+				StreamType:    tsio.ElementaryStreamTypeH265,
+				ElementaryPID: stream.pid,
+			})
+		case av.KLV:
+			elemStreams = append(elemStreams, tsio.ElementaryStreamInfo{
+				StreamType:    tsio.ElementaryStreamTypeMetadata,
+				ElementaryPID: stream.pid,
+				Descriptors: []tsio.Descriptor{
+					{
+						// application format 0x0100, format 0xff identified
+						// by KLVA, service 0, no decoder config
+						Tag:  tsio.DescriptorTagMetadata,
+						Data: []byte{0x01, 0x00, 0xff, 'K', 'L', 'V', 'A', 0x00, 0x0f},
+					},
+					{
+						// leak rates and buffer size unspecified
+						Tag:  tsio.DescriptorTagMetadataStd,
+						Data: []byte{0xc0, 0x00, 0x00, 0xc0, 0x00, 0x00, 0xc0, 0x00, 0x00},
+					},
+				},
+			})
 		}
 	}
 
@@ -199,6 +225,50 @@ func (self *Muxer) WritePacket(pkt av.Packet) (err error) {
 		if err = stream.tsw.WritePackets(self.w, datav, pkt.Time, pkt.IsKeyFrame, false); err != nil {
 			return
 		}
+
+	case av.H265:
+		codec := stream.CodecData.(h265parser.CodecData)
+
+		nalus := self.nalus[:0]
+		if pkt.IsKeyFrame {
+			nalus = append(nalus, codec.VPS())
+			nalus = append(nalus, codec.SPS())
+			nalus = append(nalus, codec.PPS())
+		}
+		pktnalus, _ := h264parser.SplitNALUs(pkt.Data)
+		for _, nalu := range pktnalus {
+			nalus = append(nalus, nalu)
+		}
+
+		datav := self.datav[:1]
+		for i, nalu := range nalus {
+			if i == 0 {
+				datav = append(datav, h265parser.AUDBytes)
+			} else {
+				datav = append(datav, h265parser.StartCodeBytes)
+			}
+			datav = append(datav, nalu)
+		}
+
+		n := tsio.FillPESHeader(self.peshdr, tsio.StreamIdH264, -1, pkt.Time+pkt.CompositionTime, pkt.Time)
+		datav[0] = self.peshdr[:n]
+
+		if err = stream.tsw.WritePackets(self.w, datav, pkt.Time, pkt.IsKeyFrame, false); err != nil {
+			return
+		}
+
+	case av.KLV:
+		// one KLV unit in a metadata access unit cell
+		n := tsio.FillPESHeader(self.peshdr, tsio.StreamIdMetadata, len(self.cellhdr)+len(pkt.Data), pkt.Time, 0)
+		self.datav[0] = self.peshdr[:n]
+		tsio.FillMetadataAUCellHeader(self.cellhdr, stream.cellSeqnum, len(pkt.Data))
+		stream.cellSeqnum++
+		self.datav[1] = self.cellhdr
+		self.datav[2] = pkt.Data
+
+		if err = stream.tsw.WritePackets(self.w, self.datav[:3], pkt.Time, false, false); err != nil {
+			return
+		}
 	}
 
 	return
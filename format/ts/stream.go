@@ -16,8 +16,9 @@ type Stream struct {
 	streamId   uint8
 	streamType uint8
 
-	tsw *tsio.TSWriter
-	idx int
+	tsw        *tsio.TSWriter
+	idx        int
+	cellSeqnum uint8
 
 	iskeyframe bool
 	pts, dts   time.Duration
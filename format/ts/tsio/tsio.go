@@ -8,8 +8,9 @@ import (
 )
 
 const (
-	StreamIdH264 = 0xe0
-	StreamIdAAC  = 0xc0
+	StreamIdH264     = 0xe0
+	StreamIdAAC      = 0xc0
+	StreamIdMetadata = 0xfc
 )
 
 const (
@@ -32,10 +33,35 @@ var ErrParsePMT = fmt.Errorf("invalid PMT")
 var ErrParsePAT = fmt.Errorf("invalid PAT")
 
 const (
-	ElementaryStreamTypeH264    = 0x1B
-	ElementaryStreamTypeAdtsAAC = 0x0F
+	ElementaryStreamTypeH264     = 0x1B
+	ElementaryStreamTypeH265     = 0x24
+	ElementaryStreamTypeAdtsAAC  = 0x0F
+	ElementaryStreamTypeMetadata = 0x15 // metadata in PES, MISB ST 1402 synchronous KLV
 )
 
+// descriptors of synchronous KLV metadata (ISO/IEC 13818-1 2.6.60, 2.6.62)
+const (
+	DescriptorTagMetadata    = 0x26
+	DescriptorTagMetadataStd = 0x27
+)
+
+// MetadataAUCellHeaderLength is the header of a cell of the metadata
+// access unit wrapper (ISO/IEC 13818-1 2.12.4).
+const MetadataAUCellHeaderLength = 5
+
+// FillMetadataAUCellHeader writes the header of a complete, random access
+// cell of datalen bytes.
+func FillMetadataAUCellHeader(h []byte, seqnum uint8, datalen int) (n int) {
+	h[0] = 0      // metadata_service_id
+	h[1] = seqnum // sequence_number
+	// cell_fragment_indication(2)=3, decoder_config_flag(1)=0,
+	// random_access_indicator(1)=1, reserved(4)
+	h[2] = 3<<6 | 1<<4 | 0xf
+	pio.PutU16BE(h[3:5], uint16(datalen))
+	n = MetadataAUCellHeaderLength
+	return
+}
+
 type PATEntry struct {
 	ProgramNumber uint16
 	NetworkPID    uint16
@@ -192,7 +218,7 @@ func (self PMT) parseDescs(b []byte) (descs []Descriptor, err error) {
 			desc.Tag = b[n]
 			desc.Data = make([]byte, b[n+1])
 			n += 2
-			if n+len(desc.Data) < len(b) {
+			if n+len(desc.Data) <= len(b) {
 				copy(desc.Data, b[n:])
 				descs = append(descs, desc)
 				n += len(desc.Data)
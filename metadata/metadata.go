@@ -0,0 +1,196 @@
+// Package metadata parses the tt:MetadataStream documents of an ONVIF
+// metadata track: event notifications, video analytics and PTZ status.
+package metadata
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/client"
+)
+
+// MetadataStream is a tt:MetadataStream document. Elements are matched by
+// local name, whatever namespace prefix the camera uses.
+type MetadataStream struct {
+	Frames        []Frame        `xml:"VideoAnalytics>Frame"`
+	PTZStatus     []PTZStatus    `xml:"PTZ>PTZStatus"`
+	Notifications []Notification `xml:"Event>NotificationMessage"`
+}
+
+// Frame is the video analytics of a video frame.
+type Frame struct {
+	UtcTime time.Time `xml:"UtcTime,attr"`
+	Objects []Object  `xml:"Object"`
+}
+
+type Object struct {
+	ObjectId        string           `xml:"ObjectId,attr"`
+	BoundingBox     *Rectangle       `xml:"Appearance>Shape>BoundingBox"`
+	CenterOfGravity *Vector          `xml:"Appearance>Shape>CenterOfGravity"`
+	Types           []ClassType      `xml:"Appearance>Class>Type"`
+	Candidates      []ClassCandidate `xml:"Appearance>Class>ClassCandidate"`
+}
+
+// Rectangle is in the normalized coordinates of the frame, -1 to 1, unless
+// the frame gives a transformation.
+type Rectangle struct {
+	Left   float64 `xml:"left,attr"`
+	Top    float64 `xml:"top,attr"`
+	Right  float64 `xml:"right,attr"`
+	Bottom float64 `xml:"bottom,attr"`
+}
+
+type Vector struct {
+	X float64 `xml:"x,attr"`
+	Y float64 `xml:"y,attr"`
+}
+
+// ClassType is an object class of ONVIF 2.x and later, e.g. Human, Vehicle.
+type ClassType struct {
+	Likelihood float64 `xml:"Likelihood,attr"`
+	Type       string  `xml:",chardata"`
+}
+
+// ClassCandidate is an object class of ONVIF 1.x.
+type ClassCandidate struct {
+	Type       string  `xml:"Type"`
+	Likelihood float64 `xml:"Likelihood"`
+}
+
+type PTZStatus struct {
+	Position   PTZVector     `xml:"Position"`
+	MoveStatus PTZMoveStatus `xml:"MoveStatus"`
+	Error      string        `xml:"Error"`
+	UtcTime    time.Time     `xml:"UtcTime"`
+}
+
+type PTZVector struct {
+	PanTilt *Vector2D `xml:"PanTilt"`
+	Zoom    *Vector1D `xml:"Zoom"`
+}
+
+type Vector2D struct {
+	X     float64 `xml:"x,attr"`
+	Y     float64 `xml:"y,attr"`
+	Space string  `xml:"space,attr"`
+}
+
+type Vector1D struct {
+	X     float64 `xml:"x,attr"`
+	Space string  `xml:"space,attr"`
+}
+
+// PTZMoveStatus is IDLE, MOVING or UNKNOWN for each axis.
+type PTZMoveStatus struct {
+	PanTilt string `xml:"PanTilt"`
+	Zoom    string `xml:"Zoom"`
+}
+
+// Notification is a wsnt:NotificationMessage, e.g. of topic
+// tns1:RuleEngine/CellMotionDetector/Motion.
+type Notification struct {
+	Topic   string  `xml:"Topic"`
+	Message Message `xml:"Message>Message"`
+}
+
+type Message struct {
+	UtcTime           time.Time    `xml:"UtcTime,attr"`
+	PropertyOperation string       `xml:"PropertyOperation,attr"` // Initialized, Changed or Deleted
+	Source            []SimpleItem `xml:"Source>SimpleItem"`
+	Key               []SimpleItem `xml:"Key>SimpleItem"`
+	Data              []SimpleItem `xml:"Data>SimpleItem"`
+}
+
+type SimpleItem struct {
+	Name  string `xml:"Name,attr"`
+	Value string `xml:"Value,attr"`
+}
+
+// Item returns the value of the item called name.
+func Item(items []SimpleItem, name string) (value string, ok bool) {
+	for _, item := range items {
+		if item.Name == name {
+			return item.Value, true
+		}
+	}
+	return
+}
+
+// Parse parses a whole tt:MetadataStream document.
+func Parse(doc []byte) (stream MetadataStream, err error) {
+	if err = xml.Unmarshal(doc, &stream); err != nil {
+		err = fmt.Errorf("metadata: parse failed: %s", err)
+		return
+	}
+	for i := range stream.Notifications {
+		stream.Notifications[i].Topic = strings.TrimSpace(stream.Notifications[i].Topic)
+	}
+	for i := range stream.Frames {
+		for j := range stream.Frames[i].Objects {
+			types := stream.Frames[i].Objects[j].Types
+			for k := range types {
+				types[k].Type = strings.TrimSpace(types[k].Type)
+			}
+		}
+	}
+	return
+}
+
+// Metadata is a document of a metadata track with the time of its packet.
+type Metadata struct {
+	MetadataStream
+	Idx       int8
+	Time      time.Duration
+	WallClock time.Time
+}
+
+// Source is what Reader reads from, a client.Client.
+type Source interface {
+	av.PacketReader
+	Streams() ([]client.Stream, error)
+}
+
+// Reader reads the metadata documents of a client, skipping the packets of
+// other tracks, KLV metadata included. The client emits a document once all
+// of its RTP packets arrived.
+type Reader struct {
+	src     Source
+	isONVIF []bool // by stream index, nil until the streams are known
+}
+
+func NewReader(src Source) *Reader {
+	return &Reader{src: src}
+}
+
+func (self *Reader) ReadMetadata() (md Metadata, err error) {
+	if self.isONVIF == nil {
+		var streams []client.Stream
+		if streams, err = self.src.Streams(); err != nil {
+			return
+		}
+		self.isONVIF = make([]bool, len(streams))
+		for i, stream := range streams {
+			self.isONVIF[i] = stream.CodecData != nil && stream.CodecData.Type() == av.ONVIF_METADATA
+		}
+	}
+
+	for {
+		var pkt av.Packet
+		if pkt, err = self.src.ReadPacket(); err != nil {
+			return
+		}
+		if pkt.Idx < 0 || int(pkt.Idx) >= len(self.isONVIF) || !self.isONVIF[pkt.Idx] {
+			continue
+		}
+		if md.MetadataStream, err = Parse(pkt.Data); err != nil {
+			return
+		}
+		md.Idx = pkt.Idx
+		md.Time = pkt.Time
+		md.WallClock = pkt.WallClock
+		return
+	}
+}
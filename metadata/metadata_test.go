@@ -0,0 +1,152 @@
+package metadata
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/client"
+	"github.com/fanap-infra/rtsp/codec"
+	"github.com/fanap-infra/rtsp/codec/klv"
+)
+
+const testDoc = `<?xml version="1.0" encoding="UTF-8"?>
+<tt:MetadataStream xmlns:tt="http://www.onvif.org/ver10/schema" xmlns:wsnt="http://docs.oasis-open.org/wsn/b-2" xmlns:tns1="http://www.onvif.org/ver10/topics">
+<tt:VideoAnalytics>
+<tt:Frame UtcTime="2024-05-01T10:20:30.500Z">
+<tt:Object ObjectId="12">
+<tt:Appearance>
+<tt:Shape>
+<tt:BoundingBox left="-0.5" top="0.25" right="0.1" bottom="-0.3"/>
+<tt:CenterOfGravity x="-0.2" y="-0.025"/>
+</tt:Shape>
+<tt:Class><tt:Type Likelihood="0.8"> Human </tt:Type></tt:Class>
+</tt:Appearance>
+</tt:Object>
+</tt:Frame>
+</tt:VideoAnalytics>
+<tt:PTZ>
+<tt:PTZStatus>
+<tt:Position>
+<tt:PanTilt x="0.5" y="-0.1" space="http://www.onvif.org/ver10/tptz/PanTiltSpaces/PositionGenericSpace"/>
+<tt:Zoom x="0.2"/>
+</tt:Position>
+<tt:MoveStatus><tt:PanTilt>MOVING</tt:PanTilt><tt:Zoom>IDLE</tt:Zoom></tt:MoveStatus>
+<tt:UtcTime>2024-05-01T10:20:30Z</tt:UtcTime>
+</tt:PTZStatus>
+</tt:PTZ>
+<tt:Event>
+<wsnt:NotificationMessage>
+<wsnt:Topic Dialect="http://www.onvif.org/ver10/tev/topicExpression/ConcreteSet">
+tns1:RuleEngine/CellMotionDetector/Motion
+</wsnt:Topic>
+<wsnt:Message>
+<tt:Message UtcTime="2024-05-01T10:20:31Z" PropertyOperation="Changed">
+<tt:Source>
+<tt:SimpleItem Name="VideoSourceConfigurationToken" Value="VideoSourceToken"/>
+<tt:SimpleItem Name="Rule" Value="MyMotionDetectorRule"/>
+</tt:Source>
+<tt:Data><tt:SimpleItem Name="IsMotion" Value="true"/></tt:Data>
+</tt:Message>
+</wsnt:Message>
+</wsnt:NotificationMessage>
+</tt:Event>
+</tt:MetadataStream>`
+
+func TestParse(t *testing.T) {
+	stream, err := Parse([]byte(testDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stream.Frames) != 1 || len(stream.Frames[0].Objects) != 1 {
+		t.Fatalf("frames=%+v", stream.Frames)
+	}
+	frame := stream.Frames[0]
+	if !frame.UtcTime.Equal(time.Date(2024, 5, 1, 10, 20, 30, 500e6, time.UTC)) {
+		t.Fatalf("frame time=%v", frame.UtcTime)
+	}
+	object := frame.Objects[0]
+	if object.ObjectId != "12" || object.BoundingBox == nil || *object.BoundingBox != (Rectangle{-0.5, 0.25, 0.1, -0.3}) {
+		t.Fatalf("object=%+v", object)
+	}
+	if object.CenterOfGravity == nil || *object.CenterOfGravity != (Vector{-0.2, -0.025}) {
+		t.Fatalf("center=%+v", object.CenterOfGravity)
+	}
+	if len(object.Types) != 1 || object.Types[0] != (ClassType{0.8, "Human"}) {
+		t.Fatalf("types=%+v", object.Types)
+	}
+
+	if len(stream.PTZStatus) != 1 {
+		t.Fatalf("ptz=%+v", stream.PTZStatus)
+	}
+	ptz := stream.PTZStatus[0]
+	if ptz.Position.PanTilt == nil || ptz.Position.PanTilt.X != 0.5 || ptz.Position.PanTilt.Y != -0.1 ||
+		ptz.Position.Zoom == nil || ptz.Position.Zoom.X != 0.2 ||
+		ptz.MoveStatus != (PTZMoveStatus{"MOVING", "IDLE"}) || ptz.UtcTime.IsZero() {
+		t.Fatalf("ptz=%+v", ptz)
+	}
+
+	if len(stream.Notifications) != 1 {
+		t.Fatalf("notifications=%+v", stream.Notifications)
+	}
+	notification := stream.Notifications[0]
+	if notification.Topic != "tns1:RuleEngine/CellMotionDetector/Motion" || notification.Message.PropertyOperation != "Changed" {
+		t.Fatalf("notification=%+v", notification)
+	}
+	if value, ok := Item(notification.Message.Data, "IsMotion"); !ok || value != "true" {
+		t.Fatalf("IsMotion=%q", value)
+	}
+	if value, _ := Item(notification.Message.Source, "Rule"); value != "MyMotionDetectorRule" {
+		t.Fatalf("Rule=%q", value)
+	}
+}
+
+var _ Source = (*client.Client)(nil)
+
+type testSource struct {
+	streams []client.Stream
+	pkts    []av.Packet
+}
+
+func (self *testSource) Streams() ([]client.Stream, error) {
+	return self.streams, nil
+}
+
+func (self *testSource) ReadPacket() (pkt av.Packet, err error) {
+	if len(self.pkts) == 0 {
+		err = io.EOF
+		return
+	}
+	pkt = self.pkts[0]
+	self.pkts = self.pkts[1:]
+	return
+}
+
+func TestReader(t *testing.T) {
+	klvUnit := append(append([]byte{}, klv.UASLocalSetKey...), 0x03, 0x41, 0x01, 0x0d)
+	r := NewReader(&testSource{
+		streams: []client.Stream{
+			{CodecData: codec.NewPCMMulawCodecData()},
+			{CodecData: klv.NewCodecData()},
+			{CodecData: codec.NewMetadataCodecData("track3")},
+		},
+		pkts: []av.Packet{
+			{Idx: 0, IsAudio: true, Data: []byte{0xff}},
+			{Idx: 1, IsMetadata: true, Time: time.Second, Data: klvUnit},
+			{Idx: 2, IsMetadata: true, Time: 2 * time.Second, Data: []byte(testDoc)},
+			{Idx: 1, IsMetadata: true, Time: 3 * time.Second, Data: klvUnit},
+		},
+	})
+	md, err := r.ReadMetadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Idx != 2 || md.Time != 2*time.Second || len(md.Notifications) != 1 {
+		t.Fatalf("metadata=%+v", md)
+	}
+	if _, err = r.ReadMetadata(); err != io.EOF {
+		t.Fatalf("err=%v", err)
+	}
+}
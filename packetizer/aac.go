@@ -0,0 +1,71 @@
+package packetizer
+
+import (
+	"fmt"
+
+	"github.com/fanap-infra/rtsp/av"
+)
+
+// AACPacketizer implements RFC 3640 (mpeg4-generic) with one AU-header per
+// packet. SizeLength and IndexLength must match the sizelength and
+// indexlength advertised in the SDP fmtp, 13/3 for AAC-hbr and 6/2 for
+// AAC-lbr. Access units larger than the MTU are fragmented.
+type AACPacketizer struct {
+	header
+	SizeLength  int
+	IndexLength int
+}
+
+func NewAACPacketizer(payloadType int, sampleRate int, sizeLength int, indexLength int) *AACPacketizer {
+	if sizeLength == 0 {
+		sizeLength = 13
+		indexLength = 3
+	}
+	return &AACPacketizer{
+		header:      newHeader(payloadType, sampleRate),
+		SizeLength:  sizeLength,
+		IndexLength: indexLength,
+	}
+}
+
+func (self *AACPacketizer) auHeaderSection(size int) []byte {
+	headerBits := self.SizeLength + self.IndexLength
+	b := make([]byte, 2+(headerBits+7)/8)
+	// AU-headers-length in bits
+	b[0] = byte(headerBits >> 8)
+	b[1] = byte(headerBits)
+	// AU-size followed by an AU-Index of 0, left aligned
+	v := uint64(size) << uint(64-self.SizeLength)
+	for i := range b[2:] {
+		b[2+i] = byte(v >> uint(56-8*i))
+	}
+	return b
+}
+
+func (self *AACPacketizer) Packetize(pkt av.Packet) (packets [][]byte, err error) {
+	if len(pkt.Data) >= 1<<uint(self.SizeLength) {
+		err = fmt.Errorf("packetizer: aac frame size=%d exceeds sizelength=%d", len(pkt.Data), self.SizeLength)
+		return
+	}
+	timestamp := self.Timestamp(pkt.Time)
+	auHeaders := self.auHeaderSection(len(pkt.Data))
+	if err = self.checkMTU(len(auHeaders)); err != nil {
+		return
+	}
+
+	// every fragment repeats the AU-header with the size of the whole AU
+	data := pkt.Data
+	maxSize := self.maxPayload() - len(auHeaders)
+	for len(data) > 0 {
+		size := len(data)
+		if size > maxSize {
+			size = maxSize
+		}
+		b := self.packet(timestamp, size == len(data), len(auHeaders)+size)
+		copy(b[rtpHeaderLength:], auHeaders)
+		copy(b[rtpHeaderLength+len(auHeaders):], data[:size])
+		packets = append(packets, b)
+		data = data[size:]
+	}
+	return
+}
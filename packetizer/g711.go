@@ -0,0 +1,36 @@
+package packetizer
+
+import (
+	"github.com/fanap-infra/rtsp/av"
+)
+
+// G711Packetizer sends μ-law (payload type 0) or A-law (payload type 8)
+// samples as they are, splitting packets larger than the MTU.
+type G711Packetizer struct {
+	header
+}
+
+func NewG711Packetizer(payloadType int) *G711Packetizer {
+	return &G711Packetizer{header: newHeader(payloadType, 8000)}
+}
+
+func (self *G711Packetizer) Packetize(pkt av.Packet) (packets [][]byte, err error) {
+	if err = self.checkMTU(0); err != nil {
+		return
+	}
+	timestamp := self.Timestamp(pkt.Time)
+	data := pkt.Data
+	for len(data) > 0 {
+		size := len(data)
+		if size > self.maxPayload() {
+			size = self.maxPayload()
+		}
+		// one byte per sample at 8 kHz
+		b := self.packet(timestamp, false, size)
+		copy(b[rtpHeaderLength:], data[:size])
+		packets = append(packets, b)
+		timestamp += uint32(size)
+		data = data[size:]
+	}
+	return
+}
@@ -0,0 +1,136 @@
+package packetizer
+
+import (
+	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/codec/h264parser"
+)
+
+// H264Packetizer implements RFC 6184 packetization mode 1: parameter sets
+// are aggregated in STAP-A packets, other NAL units are sent as single NAL
+// unit packets or split into FU-A fragments when they exceed the MTU.
+type H264Packetizer struct {
+	header
+	sps []byte
+	pps []byte
+}
+
+func NewH264Packetizer(payloadType int, sps, pps []byte) *H264Packetizer {
+	return &H264Packetizer{
+		header: newHeader(payloadType, 90000),
+		sps:    sps,
+		pps:    pps,
+	}
+}
+
+// Packetize takes an AVCC (or Annex B) access unit and sets the marker bit
+// on the last packet of it. SPS and PPS are sent ahead of key frames that do
+// not carry their own.
+func (self *H264Packetizer) Packetize(pkt av.Packet) (packets [][]byte, err error) {
+	// FU indicator and header
+	if err = self.checkMTU(2); err != nil {
+		return
+	}
+	timestamp := self.Timestamp(pkt.Time + pkt.CompositionTime)
+
+	var nalus [][]byte
+	var hasSPS bool
+	splitted, _ := h264parser.SplitNALUs(pkt.Data)
+	for _, nalu := range splitted {
+		if len(nalu) > 0 {
+			if h264parser.IsSPSNALU(nalu) {
+				hasSPS = true
+			}
+			nalus = append(nalus, nalu)
+		}
+	}
+	if pkt.IsKeyFrame && !hasSPS && len(self.sps) > 0 && len(self.pps) > 0 {
+		nalus = append([][]byte{self.sps, self.pps}, nalus...)
+	}
+
+	for i := 0; i < len(nalus); {
+		// aggregate consecutive parameter sets
+		n := 0
+		size := 1
+		for j := i; j < len(nalus) && isParameterSet(nalus[j]); j++ {
+			if size+2+len(nalus[j]) > self.maxPayload() {
+				break
+			}
+			size += 2 + len(nalus[j])
+			n++
+		}
+		if n > 1 {
+			packets = append(packets, self.stapA(timestamp, nalus[i:i+n], i+n == len(nalus)))
+			i += n
+			continue
+		}
+		packets = append(packets, self.packetizeNALU(timestamp, nalus[i], i == len(nalus)-1)...)
+		i++
+	}
+	return
+}
+
+func isParameterSet(nalu []byte) bool {
+	typ := h264parser.GetNALUType(nalu)
+	return typ == h264parser.NALU_SPS || typ == h264parser.NALU_PPS
+}
+
+// stapA packs nalus into one single-time aggregation packet, RFC 6184 5.7.1.
+func (self *H264Packetizer) stapA(timestamp uint32, nalus [][]byte, last bool) []byte {
+	size := 1
+	var nri byte
+	for _, nalu := range nalus {
+		size += 2 + len(nalu)
+		if nalu[0]&0x60 > nri {
+			nri = nalu[0] & 0x60
+		}
+	}
+	b := self.packet(timestamp, last, size)
+	payload := b[rtpHeaderLength:]
+	payload[0] = nri | 24
+	pos := 1
+	for _, nalu := range nalus {
+		payload[pos] = byte(len(nalu) >> 8)
+		payload[pos+1] = byte(len(nalu))
+		copy(payload[pos+2:], nalu)
+		pos += 2 + len(nalu)
+	}
+	return b
+}
+
+func (self *H264Packetizer) packetizeNALU(timestamp uint32, nalu []byte, last bool) (packets [][]byte) {
+	maxPayload := self.maxPayload()
+	if len(nalu) <= maxPayload {
+		b := self.packet(timestamp, last, len(nalu))
+		copy(b[rtpHeaderLength:], nalu)
+		packets = append(packets, b)
+		return
+	}
+
+	// FU-A, see RFC 6184 5.8
+	fuIndicator := nalu[0]&0xe0 | 28
+	naluType := nalu[0] & 0x1f
+	data := nalu[1:]
+	maxSize := maxPayload - 2
+	for start := true; len(data) > 0; start = false {
+		size := len(data)
+		if size > maxSize {
+			size = maxSize
+		}
+		end := size == len(data)
+
+		fuHeader := naluType
+		if start {
+			fuHeader |= 0x80
+		}
+		if end {
+			fuHeader |= 0x40
+		}
+		b := self.packet(timestamp, end && last, 2+size)
+		b[rtpHeaderLength] = fuIndicator
+		b[rtpHeaderLength+1] = fuHeader
+		copy(b[rtpHeaderLength+2:], data[:size])
+		packets = append(packets, b)
+		data = data[size:]
+	}
+	return
+}
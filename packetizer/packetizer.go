@@ -0,0 +1,116 @@
+// Package packetizer splits av.Packets into RTP packets, the reverse of the
+// depacketization done by client.Stream.
+package packetizer
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/codec/h264parser"
+	"github.com/fanap-infra/rtsp/sdp"
+)
+
+const rtpHeaderLength = 12
+
+// DefaultMTU is the largest RTP packet produced when MTU is not set,
+// including the RTP header.
+const DefaultMTU = 1400
+
+// Packetizer turns the packets of one stream into RTP packets.
+type Packetizer interface {
+	Packetize(pkt av.Packet) ([][]byte, error)
+}
+
+// header keeps the RTP state shared by every payload format. SSRC, Sequence
+// and InitialTimestamp are random and may be overridden before the first
+// Packetize call.
+type header struct {
+	PayloadType      uint8
+	SSRC             uint32
+	Sequence         uint16
+	ClockRate        int
+	InitialTimestamp uint32
+	// MTU limits the size of each RTP packet, DefaultMTU when zero.
+	MTU int
+}
+
+func randUint32() uint32 {
+	var b [4]byte
+	rand.Read(b[:])
+	return binary.BigEndian.Uint32(b[:])
+}
+
+func newHeader(payloadType int, clockRate int) header {
+	return header{
+		PayloadType:      uint8(payloadType),
+		SSRC:             randUint32(),
+		Sequence:         uint16(randUint32()),
+		ClockRate:        clockRate,
+		InitialTimestamp: randUint32(),
+	}
+}
+
+// maxPayload is the payload room left in one packet by the MTU.
+func (self *header) maxPayload() int {
+	mtu := self.MTU
+	if mtu == 0 {
+		mtu = DefaultMTU
+	}
+	return mtu - rtpHeaderLength
+}
+
+// checkMTU fails when the MTU leaves no payload room after the RTP header
+// and overhead bytes of payload headers.
+func (self *header) checkMTU(overhead int) (err error) {
+	if self.maxPayload()-overhead <= 0 {
+		err = fmt.Errorf("packetizer: mtu=%d too small", self.MTU)
+	}
+	return
+}
+
+// Timestamp converts a packet time into RTP clock units.
+func (self *header) Timestamp(tm time.Duration) uint32 {
+	return self.InitialTimestamp + uint32(int64(tm)*int64(self.ClockRate)/int64(time.Second))
+}
+
+// packet allocates an RTP packet with room for size payload bytes after the
+// header and advances the sequence number.
+func (self *header) packet(timestamp uint32, marker bool, size int) []byte {
+	b := make([]byte, rtpHeaderLength+size)
+	b[0] = 0x80
+	b[1] = self.PayloadType
+	if marker {
+		b[1] |= 0x80
+	}
+	binary.BigEndian.PutUint16(b[2:4], self.Sequence)
+	binary.BigEndian.PutUint32(b[4:8], timestamp)
+	binary.BigEndian.PutUint32(b[8:12], self.SSRC)
+	self.Sequence++
+	return b
+}
+
+// New returns a packetizer for codec matching how media describes it in
+// the SDP (payload type, AAC AU header layout), with RTP packets of at most
+// mtu bytes, DefaultMTU when zero.
+func New(media sdp.Media, codec av.CodecData, mtu int) (Packetizer, error) {
+	switch codec.Type() {
+	case av.H264:
+		h264 := codec.(h264parser.CodecData)
+		p := NewH264Packetizer(media.PayloadType, h264.SPS(), h264.PPS())
+		p.MTU = mtu
+		return p, nil
+	case av.AAC:
+		audio := codec.(av.AudioCodecData)
+		p := NewAACPacketizer(media.PayloadType, audio.SampleRate(), media.SizeLength, media.IndexLength)
+		p.MTU = mtu
+		return p, nil
+	case av.PCM_MULAW, av.PCM_ALAW:
+		p := NewG711Packetizer(media.PayloadType)
+		p.MTU = mtu
+		return p, nil
+	}
+	return nil, fmt.Errorf("packetizer: codec type=%v unsupported", codec.Type())
+}
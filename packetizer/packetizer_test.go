@@ -0,0 +1,197 @@
+package packetizer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/codec"
+	"github.com/fanap-infra/rtsp/sdp"
+)
+
+var (
+	testSPS = []byte{0x67, 0x4d, 0x00, 0x1e, 0x95, 0xa8, 0x28, 0x0f, 0x64}
+	testPPS = []byte{0x68, 0xee, 0x3c, 0x80}
+)
+
+func avcc(nalus ...[]byte) []byte {
+	var b []byte
+	for _, nalu := range nalus {
+		var l [4]byte
+		binary.BigEndian.PutUint32(l[:], uint32(len(nalu)))
+		b = append(b, l[:]...)
+		b = append(b, nalu...)
+	}
+	return b
+}
+
+func marker(b []byte) bool {
+	return b[1]&0x80 != 0
+}
+
+func TestH264Packetizer(t *testing.T) {
+	p := NewH264Packetizer(96, testSPS, testPPS)
+	p.MTU = 100
+	p.Sequence = 65535
+
+	idr := make([]byte, 250)
+	idr[0] = 0x65
+	for i := range idr[1:] {
+		idr[1+i] = byte(i)
+	}
+	packets, err := p.Packetize(av.Packet{IsKeyFrame: true, Time: time.Second, Data: avcc(idr)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// STAP-A with SPS/PPS, then the IDR split in FU-A fragments
+	if len(packets) != 4 {
+		t.Fatalf("packets=%d", len(packets))
+	}
+	stap := packets[0][rtpHeaderLength:]
+	if stap[0]&0x1f != 24 || marker(packets[0]) {
+		t.Fatalf("stap-a header=%x", stap[0])
+	}
+	if !bytes.Equal(stap[3:3+len(testSPS)], testSPS) {
+		t.Fatalf("stap-a sps=%x", stap)
+	}
+
+	var data []byte
+	for i, b := range packets {
+		if len(b) > p.MTU {
+			t.Fatalf("packet#%d size=%d", i, len(b))
+		}
+		if seq := binary.BigEndian.Uint16(b[2:4]); seq != uint16(65535+i) {
+			t.Fatalf("packet#%d seq=%d", i, seq)
+		}
+		if ts := binary.BigEndian.Uint32(b[4:8]); ts != p.InitialTimestamp+90000 {
+			t.Fatalf("packet#%d timestamp=%d", i, ts)
+		}
+		if i == 0 {
+			continue
+		}
+		fu := b[rtpHeaderLength:]
+		if fu[0]&0x1f != 28 || fu[1]&0x1f != 5 {
+			t.Fatalf("packet#%d fu-a header=%x %x", i, fu[0], fu[1])
+		}
+		if start := fu[1]&0x80 != 0; start != (i == 1) {
+			t.Fatalf("packet#%d start=%v", i, start)
+		}
+		last := i == len(packets)-1
+		if end := fu[1]&0x40 != 0; end != last || marker(b) != last {
+			t.Fatalf("packet#%d end=%v marker=%v", i, end, marker(b))
+		}
+		data = append(data, fu[2:]...)
+	}
+	if !bytes.Equal(data, idr[1:]) {
+		t.Fatal("fu-a payload mismatch")
+	}
+
+	// small non-key frames go as single NAL units
+	slice := []byte{0x41, 0x9a, 0x00}
+	if packets, err = p.Packetize(av.Packet{Data: avcc(slice)}); err != nil {
+		t.Fatal(err)
+	}
+	if len(packets) != 1 || !marker(packets[0]) || !bytes.Equal(packets[0][rtpHeaderLength:], slice) {
+		t.Fatalf("single nalu packets=%x", packets)
+	}
+}
+
+func TestAACPacketizer(t *testing.T) {
+	frame := make([]byte, 300)
+
+	p := NewAACPacketizer(97, 44100, 13, 3)
+	packets, err := p.Packetize(av.Packet{Data: frame})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packets) != 1 || !marker(packets[0]) {
+		t.Fatalf("packets=%d", len(packets))
+	}
+	header := packets[0][rtpHeaderLength : rtpHeaderLength+4]
+	if !bytes.Equal(header, []byte{0x00, 0x10, 300 >> 5, (300 & 0x1f) << 3}) {
+		t.Fatalf("au header section=%x", header)
+	}
+
+	p = NewAACPacketizer(97, 44100, 6, 2)
+	if _, err = p.Packetize(av.Packet{Data: frame}); err == nil {
+		t.Fatal("frame larger than sizelength allows accepted")
+	}
+	if packets, err = p.Packetize(av.Packet{Data: frame[:40]}); err != nil {
+		t.Fatal(err)
+	}
+	header = packets[0][rtpHeaderLength : rtpHeaderLength+3]
+	if !bytes.Equal(header, []byte{0x00, 0x08, 40 << 2}) {
+		t.Fatalf("au header section=%x", header)
+	}
+
+	p = NewAACPacketizer(97, 44100, 13, 3)
+	p.MTU = 200
+	if packets, err = p.Packetize(av.Packet{Data: frame}); err != nil {
+		t.Fatal(err)
+	}
+	if len(packets) != 2 || marker(packets[0]) || !marker(packets[1]) {
+		t.Fatalf("fragments=%d", len(packets))
+	}
+}
+
+func TestG711Packetizer(t *testing.T) {
+	p := NewG711Packetizer(0)
+	p.MTU = 112
+	packets, err := p.Packetize(av.Packet{Time: time.Second, Data: make([]byte, 250)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packets) != 3 {
+		t.Fatalf("packets=%d", len(packets))
+	}
+	for i, b := range packets {
+		if ts := binary.BigEndian.Uint32(b[4:8]); ts != p.InitialTimestamp+8000+uint32(i*100) {
+			t.Fatalf("packet#%d timestamp=%d", i, ts)
+		}
+	}
+}
+
+func TestSmallMTU(t *testing.T) {
+	h264 := NewH264Packetizer(96, testSPS, testPPS)
+	h264.MTU = 14
+	aac := NewAACPacketizer(97, 44100, 13, 3)
+	aac.MTU = 16
+	g711 := NewG711Packetizer(0)
+	g711.MTU = 12
+
+	idr := append([]byte{0x65}, make([]byte, 100)...)
+	for i, c := range []struct {
+		p    Packetizer
+		data []byte
+	}{
+		{h264, avcc(idr)},
+		{aac, make([]byte, 100)},
+		{g711, make([]byte, 100)},
+	} {
+		if _, err := c.p.Packetize(av.Packet{Data: c.data}); err == nil {
+			t.Fatalf("packetizer#%d accepted a too small mtu", i)
+		}
+	}
+}
+
+func TestNewMTU(t *testing.T) {
+	p, err := New(sdp.Media{PayloadType: 0}, codec.NewPCMMulawCodecData(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packets, err := p.Packetize(av.Packet{Data: make([]byte, 1000)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packets) != 12 {
+		t.Fatalf("packets=%d", len(packets))
+	}
+	for i, b := range packets {
+		if len(b) > 100 {
+			t.Fatalf("packet#%d len=%d", i, len(b))
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package rtcp
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Goodbye tells that the sources leave the session.
+type Goodbye struct {
+	Sources []uint32
+	Reason  string
+}
+
+func parseGoodbye(count int, b []byte) (self *Goodbye, err error) {
+	if len(b) < count*4 {
+		err = fmt.Errorf("rtcp: bye truncated")
+		return
+	}
+	self = &Goodbye{}
+	for i := 0; i < count; i++ {
+		self.Sources = append(self.Sources, binary.BigEndian.Uint32(b[i*4:]))
+	}
+	b = b[count*4:]
+	if len(b) > 0 && 1+int(b[0]) <= len(b) {
+		self.Reason = string(b[1 : 1+b[0]])
+	}
+	return
+}
+
+func (self *Goodbye) Marshal() []byte {
+	b := make([]byte, headerLength+len(self.Sources)*4)
+	for i, ssrc := range self.Sources {
+		binary.BigEndian.PutUint32(b[headerLength+i*4:], ssrc)
+	}
+	if reason := self.Reason; reason != "" {
+		if len(reason) > 255 {
+			reason = reason[:255]
+		}
+		b = append(b, byte(len(reason)))
+		b = append(b, reason...)
+		for len(b)%4 != 0 {
+			b = append(b, 0)
+		}
+	}
+	putHeader(b, len(self.Sources), TypeGoodbye)
+	return b
+}
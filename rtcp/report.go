@@ -0,0 +1,127 @@
+package rtcp
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const reportLength = 24
+
+// ReceptionReport is the reception quality of one source.
+type ReceptionReport struct {
+	SSRC uint32
+	// FractionLost is the fraction of packets lost since the previous
+	// report, in 1/256.
+	FractionLost uint8
+	// TotalLost is the cumulative number of packets lost, 24 bits signed.
+	TotalLost int32
+	// HighestSequence is the highest sequence number received extended by
+	// the count of wraparounds.
+	HighestSequence uint32
+	// Jitter is the interarrival jitter in timestamp units.
+	Jitter uint32
+	// LastSR is the middle 32 bits of the NTP time of the last SR received.
+	LastSR uint32
+	// DelaySinceLastSR is in units of 1/65536 seconds.
+	DelaySinceLastSR uint32
+}
+
+func (self *ReceptionReport) unmarshal(b []byte) {
+	self.SSRC = binary.BigEndian.Uint32(b[0:4])
+	self.FractionLost = b[4]
+	lost := uint32(b[5])<<16 | uint32(b[6])<<8 | uint32(b[7])
+	self.TotalLost = int32(lost<<8) >> 8
+	self.HighestSequence = binary.BigEndian.Uint32(b[8:12])
+	self.Jitter = binary.BigEndian.Uint32(b[12:16])
+	self.LastSR = binary.BigEndian.Uint32(b[16:20])
+	self.DelaySinceLastSR = binary.BigEndian.Uint32(b[20:24])
+}
+
+func (self *ReceptionReport) marshal(b []byte) {
+	binary.BigEndian.PutUint32(b[0:4], self.SSRC)
+	lost := uint32(self.TotalLost) & 0xffffff
+	binary.BigEndian.PutUint32(b[4:8], uint32(self.FractionLost)<<24|lost)
+	binary.BigEndian.PutUint32(b[8:12], self.HighestSequence)
+	binary.BigEndian.PutUint32(b[12:16], self.Jitter)
+	binary.BigEndian.PutUint32(b[16:20], self.LastSR)
+	binary.BigEndian.PutUint32(b[20:24], self.DelaySinceLastSR)
+}
+
+func parseReports(count int, b []byte) (reports []ReceptionReport, err error) {
+	if len(b) < count*reportLength {
+		err = fmt.Errorf("rtcp: report blocks truncated")
+		return
+	}
+	reports = make([]ReceptionReport, count)
+	for i := range reports {
+		reports[i].unmarshal(b[i*reportLength:])
+	}
+	return
+}
+
+// SenderReport relates the RTP timestamps of a source to wall clock time.
+type SenderReport struct {
+	SSRC uint32
+	// NTPTime is a 64-bit NTP timestamp, see utils.NTPToTime.
+	NTPTime     uint64
+	RTPTime     uint32
+	PacketCount uint32
+	OctetCount  uint32
+	Reports     []ReceptionReport
+}
+
+func parseSenderReport(count int, b []byte) (self *SenderReport, err error) {
+	if len(b) < 24 {
+		err = fmt.Errorf("rtcp: sender report too short")
+		return
+	}
+	self = &SenderReport{
+		SSRC:        binary.BigEndian.Uint32(b[0:4]),
+		NTPTime:     binary.BigEndian.Uint64(b[4:12]),
+		RTPTime:     binary.BigEndian.Uint32(b[12:16]),
+		PacketCount: binary.BigEndian.Uint32(b[16:20]),
+		OctetCount:  binary.BigEndian.Uint32(b[20:24]),
+	}
+	self.Reports, err = parseReports(count, b[24:])
+	return
+}
+
+func (self *SenderReport) Marshal() []byte {
+	b := make([]byte, headerLength+24+len(self.Reports)*reportLength)
+	putHeader(b, len(self.Reports), TypeSenderReport)
+	binary.BigEndian.PutUint32(b[4:8], self.SSRC)
+	binary.BigEndian.PutUint64(b[8:16], self.NTPTime)
+	binary.BigEndian.PutUint32(b[16:20], self.RTPTime)
+	binary.BigEndian.PutUint32(b[20:24], self.PacketCount)
+	binary.BigEndian.PutUint32(b[24:28], self.OctetCount)
+	for i := range self.Reports {
+		self.Reports[i].marshal(b[28+i*reportLength:])
+	}
+	return b
+}
+
+// ReceiverReport is sent by participants that are not senders.
+type ReceiverReport struct {
+	SSRC    uint32
+	Reports []ReceptionReport
+}
+
+func parseReceiverReport(count int, b []byte) (self *ReceiverReport, err error) {
+	if len(b) < 4 {
+		err = fmt.Errorf("rtcp: receiver report too short")
+		return
+	}
+	self = &ReceiverReport{SSRC: binary.BigEndian.Uint32(b[0:4])}
+	self.Reports, err = parseReports(count, b[4:])
+	return
+}
+
+func (self *ReceiverReport) Marshal() []byte {
+	b := make([]byte, headerLength+4+len(self.Reports)*reportLength)
+	putHeader(b, len(self.Reports), TypeReceiverReport)
+	binary.BigEndian.PutUint32(b[4:8], self.SSRC)
+	for i := range self.Reports {
+		self.Reports[i].marshal(b[8+i*reportLength:])
+	}
+	return b
+}
@@ -0,0 +1,101 @@
+// Package rtcp reads and writes the RTCP packets of RFC 3550 section 6 used
+// by an RTSP session: sender and receiver reports, source descriptions and
+// goodbyes.
+package rtcp
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	TypeSenderReport      = 200
+	TypeReceiverReport    = 201
+	TypeSourceDescription = 202
+	TypeGoodbye           = 203
+	TypeApplication       = 204
+)
+
+const headerLength = 4
+
+// Packet is one RTCP packet of a compound packet.
+type Packet interface {
+	// Marshal encodes the packet including its header.
+	Marshal() []byte
+}
+
+func putHeader(b []byte, count int, typ uint8) {
+	/*
+		0                   1                   2                   3
+		0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+		+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+		|V=2|P|    RC   |       PT      |             length            |
+		+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	*/
+	b[0] = 0x80 | byte(count&0x1f)
+	b[1] = typ
+	binary.BigEndian.PutUint16(b[2:4], uint16(len(b)/4-1))
+}
+
+// Unmarshal splits a compound RTCP packet. Packet types other than SR, RR,
+// SDES and BYE are skipped.
+func Unmarshal(b []byte) (packets []Packet, err error) {
+	for len(b) > 0 {
+		if len(b) < headerLength {
+			err = fmt.Errorf("rtcp: packet too short")
+			return
+		}
+		if b[0]&0xc0 != 0x80 {
+			err = fmt.Errorf("rtcp: version=%d invalid", b[0]>>6)
+			return
+		}
+		size := (int(binary.BigEndian.Uint16(b[2:4])) + 1) * 4
+		if size > len(b) {
+			err = fmt.Errorf("rtcp: length=%d exceeds packet", size)
+			return
+		}
+		count := int(b[0] & 0x1f)
+		body := b[headerLength:size]
+		if b[0]&0x20 != 0 { // P, padding count in the last byte
+			if len(body) == 0 {
+				err = fmt.Errorf("rtcp: padding invalid")
+				return
+			}
+			pad := int(body[len(body)-1])
+			if pad == 0 || pad > len(body) {
+				err = fmt.Errorf("rtcp: padding invalid")
+				return
+			}
+			body = body[:len(body)-pad]
+		}
+
+		var pkt Packet
+		switch b[1] {
+		case TypeSenderReport:
+			pkt, err = parseSenderReport(count, body)
+		case TypeReceiverReport:
+			pkt, err = parseReceiverReport(count, body)
+		case TypeSourceDescription:
+			pkt, err = parseSourceDescription(count, body)
+		case TypeGoodbye:
+			pkt, err = parseGoodbye(count, body)
+		}
+		if err != nil {
+			return
+		}
+		if pkt != nil {
+			packets = append(packets, pkt)
+		}
+		b = b[size:]
+	}
+	return
+}
+
+// Marshal builds a compound packet.
+func Marshal(packets ...Packet) []byte {
+	var b []byte
+	for _, pkt := range packets {
+		b = append(b, pkt.Marshal()...)
+	}
+	return b
+}
@@ -0,0 +1,66 @@
+package rtcp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMarshalUnmarshal(t *testing.T) {
+	packets := []Packet{
+		&SenderReport{
+			SSRC:        0x11223344,
+			NTPTime:     0xe1234567_89abcdef,
+			RTPTime:     90000,
+			PacketCount: 10,
+			OctetCount:  12000,
+			Reports: []ReceptionReport{
+				{SSRC: 1, FractionLost: 25, TotalLost: -3, HighestSequence: 70000, Jitter: 12, LastSR: 0x456789ab, DelaySinceLastSR: 65536},
+			},
+		},
+		&ReceiverReport{
+			SSRC:    0x55667788,
+			Reports: []ReceptionReport{{SSRC: 0x11223344, TotalLost: 100}},
+		},
+		&SourceDescription{
+			Chunks: []SDESChunk{
+				{Source: 0x55667788, Items: []SDESItem{{Type: SDESCNAME, Text: "cam@10.0.0.1"}}},
+				{Source: 0x11223344, Items: []SDESItem{{Type: SDESCNAME, Text: "ab"}, {Type: SDESTool, Text: "rtsp"}}},
+			},
+		},
+		&Goodbye{Sources: []uint32{0x11223344}, Reason: "end of stream"},
+	}
+
+	b := Marshal(packets...)
+	if len(b)%4 != 0 {
+		t.Fatalf("compound length=%d not aligned", len(b))
+	}
+	got, err := Unmarshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, packets) {
+		t.Fatalf("got %+v", got)
+	}
+	if cname := got[2].(*SourceDescription).Chunks[0].CNAME(); cname != "cam@10.0.0.1" {
+		t.Fatalf("cname=%q", cname)
+	}
+}
+
+func TestUnmarshalTruncated(t *testing.T) {
+	b := (&ReceiverReport{SSRC: 1, Reports: []ReceptionReport{{SSRC: 2}}}).Marshal()
+	if _, err := Unmarshal(b[:len(b)-4]); err == nil {
+		t.Fatal("truncated packet accepted")
+	}
+}
+
+func TestUnmarshalPadding(t *testing.T) {
+	for _, b := range [][]byte{
+		{0xa0, TypeReceiverReport, 0, 0},             // padding without a body
+		{0xa0, TypeReceiverReport, 0, 1, 0, 0, 0, 0}, // zero padding count
+		{0xa0, TypeReceiverReport, 0, 1, 0, 0, 0, 5}, // padding exceeds body
+	} {
+		if _, err := Unmarshal(b); err == nil {
+			t.Fatalf("packet %x accepted", b)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package rtcp
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	SDESEnd   = 0
+	SDESCNAME = 1
+	SDESName  = 2
+	SDESEmail = 3
+	SDESPhone = 4
+	SDESLoc   = 5
+	SDESTool  = 6
+	SDESNote  = 7
+	SDESPriv  = 8
+)
+
+type SDESItem struct {
+	Type uint8
+	Text string
+}
+
+// SDESChunk describes one source.
+type SDESChunk struct {
+	Source uint32
+	Items  []SDESItem
+}
+
+// CNAME returns the canonical name item of the chunk, empty when missing.
+func (self SDESChunk) CNAME() string {
+	for _, item := range self.Items {
+		if item.Type == SDESCNAME {
+			return item.Text
+		}
+	}
+	return ""
+}
+
+type SourceDescription struct {
+	Chunks []SDESChunk
+}
+
+func parseSourceDescription(count int, b []byte) (self *SourceDescription, err error) {
+	self = &SourceDescription{}
+	for i := 0; i < count; i++ {
+		if len(b) < 4 {
+			err = fmt.Errorf("rtcp: sdes chunk truncated")
+			return
+		}
+		chunk := SDESChunk{Source: binary.BigEndian.Uint32(b[0:4])}
+		pos := 4
+		for {
+			if pos >= len(b) {
+				err = fmt.Errorf("rtcp: sdes items truncated")
+				return
+			}
+			if b[pos] == SDESEnd {
+				// the null item and padding up to the next 32-bit boundary
+				pos = (pos + 4) &^ 3
+				break
+			}
+			if pos+2 > len(b) || pos+2+int(b[pos+1]) > len(b) {
+				err = fmt.Errorf("rtcp: sdes item truncated")
+				return
+			}
+			n := int(b[pos+1])
+			chunk.Items = append(chunk.Items, SDESItem{Type: b[pos], Text: string(b[pos+2 : pos+2+n])})
+			pos += 2 + n
+		}
+		self.Chunks = append(self.Chunks, chunk)
+		if pos > len(b) {
+			pos = len(b)
+		}
+		b = b[pos:]
+	}
+	return
+}
+
+func (self *SourceDescription) Marshal() []byte {
+	b := make([]byte, headerLength)
+	for _, chunk := range self.Chunks {
+		var ssrc [4]byte
+		binary.BigEndian.PutUint32(ssrc[:], chunk.Source)
+		start := len(b)
+		b = append(b, ssrc[:]...)
+		for _, item := range chunk.Items {
+			text := item.Text
+			if len(text) > 255 {
+				text = text[:255]
+			}
+			b = append(b, item.Type, byte(len(text)))
+			b = append(b, text...)
+		}
+		// at least one null octet ends the item list
+		b = append(b, SDESEnd)
+		for (len(b)-start)%4 != 0 {
+			b = append(b, 0)
+		}
+	}
+	putHeader(b, len(self.Chunks), TypeSourceDescription)
+	return b
+}
@@ -18,13 +18,45 @@ type Media struct {
 	AVType             string
 	Type               av.CodecType
 	TimeScale          int
+	Channels           int
 	Control            string
 	Rtpmap             int
+	EncodingName       string
 	Config             []byte
 	SpropParameterSets [][]byte
 	PayloadType        int
 	SizeLength         int
 	IndexLength        int
+	IndexDeltaLength   int
+	CTSDeltaLength     int
+	DTSDeltaLength     int
+	// Direction is sendonly, recvonly, sendrecv or inactive when given.
+	// ONVIF marks the backchannel a client sends audio on as sendonly.
+	Direction string
+	// H265 parameter sets from sprop-vps/sprop-sps/sprop-pps (RFC 7798)
+	SpropVPS [][]byte
+	SpropSPS [][]byte
+	SpropPPS [][]byte
+	// Frame size from a=framesize or a=x-dimensions, JPEG has no fmtp for it
+	Width  int
+	Height int
+	// MP4A-LATM (RFC 6416) AAC, Config then holds the StreamMuxConfig.
+	// CPresent is set when StreamMuxConfig is also sent in band, the
+	// default unless the fmtp has cpresent=0.
+	LATM     bool
+	CPresent bool
+}
+
+// static payload types of RFC 3551 handled without an rtpmap
+var staticPayloadTypes = map[int]struct {
+	typ       av.CodecType
+	timeScale int
+	channels  int
+}{
+	9:  {av.G722, 8000, 1},
+	10: {av.L16, 44100, 2},
+	11: {av.L16, 44100, 1},
+	26: {av.MJPEG, 90000, 0},
 }
 
 func Parse(content string) (sess Session, medias []Media) {
@@ -47,6 +79,11 @@ func Parse(content string) (sess Session, medias []Media) {
 						if len(mfields) >= 3 {
 							media.PayloadType, _ = strconv.Atoi(mfields[2])
 						}
+						if static, ok := staticPayloadTypes[media.PayloadType]; ok { // often without rtpmap
+							media.Type = static.typ
+							media.TimeScale = static.timeScale
+							media.Channels = static.channels
+						}
 					default: // daneshvar.ho: start of other media
 						media = nil
 					}
@@ -58,6 +95,10 @@ func Parse(content string) (sess Session, medias []Media) {
 			case "a":
 				if media != nil {
 					for _, field := range fields {
+						switch field {
+						case "sendonly", "recvonly", "sendrecv", "inactive":
+							media.Direction = field
+						}
 						keyval := strings.SplitN(field, ":", 2)
 						if len(keyval) >= 2 {
 							key := keyval[0]
@@ -67,22 +108,55 @@ func Parse(content string) (sess Session, medias []Media) {
 								media.Control = val
 							case "rtpmap":
 								media.Rtpmap, _ = strconv.Atoi(val)
+							case "x-dimensions": // a=x-dimensions:640,480
+								media.Width, media.Height = parseFrameSize(strings.TrimPrefix(typeval[1], "x-dimensions:"), ",")
+							case "framesize": // a=framesize:26 640-480
+								if len(fields) == 2 {
+									media.Width, media.Height = parseFrameSize(fields[1], "-")
+								}
 							}
 						}
 						keyval = strings.Split(field, "/")
 						if len(keyval) >= 2 {
 							key := keyval[0]
+							if strings.HasPrefix(fields[0], "rtpmap:") {
+								media.EncodingName = key
+							}
 							switch strings.ToUpper(key) {
 							case "MPEG4-GENERIC":
 								media.Type = av.AAC
+							case "MP4A-LATM":
+								media.Type = av.AAC
+								media.LATM = true
+								media.CPresent = true // RFC 6416 default, cleared by cpresent=0
 							case "H264":
 								media.Type = av.H264
+							case "H265":
+								media.Type = av.H265
+							case "JPEG":
+								media.Type = av.MJPEG
+							case "G722":
+								media.Type = av.G722
+							case "G726-16", "G726-24", "G726-32", "G726-40",
+								"AAL2-G726-16", "AAL2-G726-24", "AAL2-G726-32", "AAL2-G726-40":
+								media.Type = av.G726
+							case "L16":
+								media.Type = av.L16
+							case "OPUS":
+								media.Type = av.OPUS
 							case "VND.ONVIF.METADATA": // daneshvar.ho
 								media.Type = av.ONVIF_METADATA
+							case "SMPTE336M": // RFC 6597
+								media.Type = av.KLV
 							}
 							if i, err := strconv.Atoi(keyval[1]); err == nil {
 								media.TimeScale = i
 							}
+							if len(keyval) >= 3 {
+								if i, err := strconv.Atoi(keyval[2]); err == nil {
+									media.Channels = i
+								}
+							}
 							if false {
 								fmt.Println("sdp:", keyval[1], media.TimeScale)
 							}
@@ -92,7 +166,7 @@ func Parse(content string) (sess Session, medias []Media) {
 							for _, field := range keyval {
 								keyval := strings.SplitN(field, "=", 2)
 								if len(keyval) == 2 {
-									key := strings.TrimSpace(keyval[0])
+									key := strings.ToLower(strings.TrimSpace(keyval[0])) // RFC 3640 names are case-insensitive
 									val := keyval[1]
 									switch key {
 									case "config":
@@ -101,12 +175,26 @@ func Parse(content string) (sess Session, medias []Media) {
 										media.SizeLength, _ = strconv.Atoi(val)
 									case "indexlength":
 										media.IndexLength, _ = strconv.Atoi(val)
+									case "indexdeltalength":
+										media.IndexDeltaLength, _ = strconv.Atoi(val)
+									case "ctsdeltalength":
+										media.CTSDeltaLength, _ = strconv.Atoi(val)
+									case "dtsdeltalength":
+										media.DTSDeltaLength, _ = strconv.Atoi(val)
+									case "cpresent":
+										media.CPresent = strings.TrimSpace(val) != "0"
 									case "sprop-parameter-sets":
 										fields := strings.Split(val, ",")
 										for _, field := range fields {
 											val, _ := base64.StdEncoding.DecodeString(field)
 											media.SpropParameterSets = append(media.SpropParameterSets, val)
 										}
+									case "sprop-vps":
+										media.SpropVPS = decodeSprop(val)
+									case "sprop-sps":
+										media.SpropSPS = decodeSprop(val)
+									case "sprop-pps":
+										media.SpropPPS = decodeSprop(val)
 									}
 								}
 							}
@@ -119,3 +207,22 @@ func Parse(content string) (sess Session, medias []Media) {
 	}
 	return
 }
+
+// decodeSprop decodes a comma separated list of base64 parameter sets.
+func decodeSprop(val string) (sets [][]byte) {
+	for _, field := range strings.Split(val, ",") {
+		if b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(field)); err == nil && len(b) > 0 {
+			sets = append(sets, b)
+		}
+	}
+	return
+}
+
+func parseFrameSize(val string, sep string) (width, height int) {
+	size := strings.SplitN(strings.TrimSpace(val), sep, 2)
+	if len(size) == 2 {
+		width, _ = strconv.Atoi(strings.TrimSpace(size[0]))
+		height, _ = strconv.Atoi(strings.TrimSpace(size[1]))
+	}
+	return
+}
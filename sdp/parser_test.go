@@ -1,5 +1,65 @@
 package sdp
 
+import (
+	"testing"
+
+	"github.com/fanap-infra/rtsp/av"
+)
+
+func TestParseAudioRtpmap(t *testing.T) {
+	_, medias := Parse("v=0\r\n" +
+		"m=audio 0 RTP/AVP 9\r\n" +
+		"m=audio 0 RTP/AVP 11\r\n" +
+		"m=audio 0 RTP/AVP 97\r\n" +
+		"a=rtpmap:97 G726-24/8000\r\n" +
+		"a=control:rtsp://127.0.0.1/track/3\r\n" +
+		"m=audio 0 RTP/AVP 98\r\n" +
+		"a=rtpmap:98 L16/16000/2\r\n" +
+		"m=audio 0 RTP/AVP 111\r\n" +
+		"a=rtpmap:111 opus/48000/2\r\n" +
+		"m=video 0 RTP/AVP 26\r\n" +
+		"a=x-dimensions: 720, 480\r\n")
+
+	for i, want := range []struct {
+		typ       av.CodecType
+		name      string
+		timeScale int
+		channels  int
+	}{
+		{av.G722, "", 8000, 1},
+		{av.L16, "", 44100, 1},
+		{av.G726, "G726-24", 8000, 0},
+		{av.L16, "L16", 16000, 2},
+		{av.OPUS, "opus", 48000, 2},
+		{av.MJPEG, "", 90000, 0},
+	} {
+		media := medias[i]
+		if media.Type != want.typ || media.EncodingName != want.name || media.TimeScale != want.timeScale || media.Channels != want.channels {
+			t.Fatalf("media#%d=%+v", i, media)
+		}
+	}
+	if medias[5].Width != 720 || medias[5].Height != 480 {
+		t.Fatalf("jpeg size=%dx%d", medias[5].Width, medias[5].Height)
+	}
+}
+
+func TestParseLATMCPresent(t *testing.T) {
+	_, medias := Parse("v=0\r\n" +
+		"m=audio 0 RTP/AVP 98\r\n" +
+		"a=rtpmap:98 MP4A-LATM/44100/2\r\n" +
+		"a=fmtp:98 profile-level-id=15;object=2\r\n" +
+		"m=audio 0 RTP/AVP 99\r\n" +
+		"a=rtpmap:99 MP4A-LATM/44100/2\r\n" +
+		"a=fmtp:99 profile-level-id=15;cpresent=0;config=400024203FC0\r\n")
+
+	if !medias[0].LATM || !medias[0].CPresent {
+		t.Fatalf("media#0=%+v, cpresent defaults to 1", medias[0])
+	}
+	if !medias[1].LATM || medias[1].CPresent {
+		t.Fatalf("media#1=%+v", medias[1])
+	}
+}
+
 // import (
 // 	"testing"
 // )
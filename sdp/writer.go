@@ -0,0 +1,169 @@
+package sdp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/codec/aacparser"
+	"github.com/fanap-infra/rtsp/codec/h264parser"
+)
+
+// NewMediaFromCodecData describes a stream as an SDP media section. Codecs
+// without a static payload type get payloadType, which should be dynamic (96-127).
+func NewMediaFromCodecData(codec av.CodecData, payloadType int) (media Media, err error) {
+	media.Type = codec.Type()
+	media.PayloadType = payloadType
+
+	switch codec.Type() {
+	case av.H264:
+		h264 := codec.(h264parser.CodecData)
+		media.AVType = "video"
+		media.TimeScale = 90000
+		media.SpropParameterSets = [][]byte{h264.SPS(), h264.PPS()}
+
+	case av.AAC:
+		aac := codec.(aacparser.CodecData)
+		media.AVType = "audio"
+		media.TimeScale = aac.SampleRate()
+		media.Channels = aac.ChannelLayout().Count()
+		media.Config = aac.MPEG4AudioConfigBytes()
+		media.SizeLength = 13
+		media.IndexLength = 3
+		media.IndexDeltaLength = 3
+
+	case av.PCM_MULAW:
+		media.AVType = "audio"
+		media.TimeScale = 8000
+		media.PayloadType = 0
+
+	case av.PCM_ALAW:
+		media.AVType = "audio"
+		media.TimeScale = 8000
+		media.PayloadType = 8
+
+	default:
+		err = fmt.Errorf("sdp: codec type=%v unsupported", codec.Type())
+		return
+	}
+	return
+}
+
+func (self Media) encodingName() string {
+	switch self.Type {
+	case av.H264:
+		return "H264"
+	case av.H265:
+		return "H265"
+	case av.MJPEG:
+		return "JPEG"
+	case av.G722:
+		return "G722"
+	case av.G726:
+		return self.EncodingName
+	case av.L16:
+		return "L16"
+	case av.OPUS:
+		return "opus"
+	case av.AAC:
+		if self.LATM {
+			return "MP4A-LATM"
+		}
+		return "MPEG4-GENERIC"
+	case av.PCM_MULAW:
+		return "PCMU"
+	case av.PCM_ALAW:
+		return "PCMA"
+	case av.ONVIF_METADATA:
+		return "vnd.onvif.metadata"
+	case av.KLV:
+		return "smpte336m"
+	}
+	return ""
+}
+
+func (self Media) fmtp() string {
+	switch self.Type {
+	case av.H264:
+		params := []string{"packetization-mode=1"}
+		if len(self.SpropParameterSets) > 0 && len(self.SpropParameterSets[0]) >= 4 {
+			params = append(params, "profile-level-id="+hex.EncodeToString(self.SpropParameterSets[0][1:4]))
+		}
+		var sets []string
+		for _, set := range self.SpropParameterSets {
+			sets = append(sets, base64.StdEncoding.EncodeToString(set))
+		}
+		if len(sets) > 0 {
+			params = append(params, "sprop-parameter-sets="+strings.Join(sets, ","))
+		}
+		return strings.Join(params, ";")
+
+	case av.H265:
+		var params []string
+		for _, sprop := range []struct {
+			name string
+			sets [][]byte
+		}{
+			{"sprop-vps", self.SpropVPS},
+			{"sprop-sps", self.SpropSPS},
+			{"sprop-pps", self.SpropPPS},
+		} {
+			var sets []string
+			for _, set := range sprop.sets {
+				sets = append(sets, base64.StdEncoding.EncodeToString(set))
+			}
+			if len(sets) > 0 {
+				params = append(params, sprop.name+"="+strings.Join(sets, ","))
+			}
+		}
+		return strings.Join(params, ";")
+
+	case av.AAC:
+		if self.LATM {
+			cpresent := 0
+			if self.CPresent {
+				cpresent = 1
+			}
+			return fmt.Sprintf("cpresent=%d;config=%s", cpresent, hex.EncodeToString(self.Config))
+		}
+		return fmt.Sprintf("streamtype=5;profile-level-id=1;mode=AAC-hbr;sizelength=%d;indexlength=%d;indexdeltalength=%d;config=%s",
+			self.SizeLength, self.IndexLength, self.IndexDeltaLength, hex.EncodeToString(self.Config))
+	}
+	return ""
+}
+
+// Marshal generates a session description for medias, the reverse of Parse.
+func Marshal(sess Session, medias []Media) string {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "v=0\r\n")
+	fmt.Fprintf(buf, "o=- 0 0 IN IP4 0.0.0.0\r\n")
+	fmt.Fprintf(buf, "s=Stream\r\n")
+	if sess.Uri != "" {
+		fmt.Fprintf(buf, "u=%s\r\n", sess.Uri)
+	}
+	fmt.Fprintf(buf, "c=IN IP4 0.0.0.0\r\n")
+	fmt.Fprintf(buf, "t=0 0\r\n")
+	fmt.Fprintf(buf, "a=control:*\r\n")
+
+	for _, media := range medias {
+		fmt.Fprintf(buf, "m=%s 0 RTP/AVP %d\r\n", media.AVType, media.PayloadType)
+		rtpmap := fmt.Sprintf("%s/%d", media.encodingName(), media.TimeScale)
+		if media.Channels > 0 {
+			rtpmap += fmt.Sprintf("/%d", media.Channels)
+		}
+		fmt.Fprintf(buf, "a=rtpmap:%d %s\r\n", media.PayloadType, rtpmap)
+		if fmtp := media.fmtp(); fmtp != "" {
+			fmt.Fprintf(buf, "a=fmtp:%d %s\r\n", media.PayloadType, fmtp)
+		}
+		if media.Direction != "" {
+			fmt.Fprintf(buf, "a=%s\r\n", media.Direction)
+		}
+		if media.Control != "" {
+			fmt.Fprintf(buf, "a=control:%s\r\n", media.Control)
+		}
+	}
+	return buf.String()
+}
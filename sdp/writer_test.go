@@ -0,0 +1,66 @@
+package sdp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/codec/aacparser"
+)
+
+func TestMarshalParse(t *testing.T) {
+	aac, err := aacparser.NewCodecDataFromMPEG4AudioConfigBytes([]byte{0x14, 0x08})
+	if err != nil {
+		t.Fatal(err)
+	}
+	media, err := NewMediaFromCodecData(aac, 97)
+	if err != nil {
+		t.Fatal(err)
+	}
+	media.Control = "streamid=0"
+
+	_, medias := Parse(Marshal(Session{}, []Media{media}))
+	if len(medias) != 1 {
+		t.Fatalf("got %d medias", len(medias))
+	}
+	got := medias[0]
+	if got.Type != av.AAC || got.PayloadType != 97 || got.TimeScale != 16000 || got.Channels != 1 {
+		t.Fatalf("unexpected %+v", got)
+	}
+	if got.SizeLength != 13 || got.IndexLength != 3 || got.IndexDeltaLength != 3 || !bytes.Equal(got.Config, media.Config) || got.Control != "streamid=0" {
+		t.Fatalf("unexpected %+v", got)
+	}
+
+	// a layout with differing index and index delta lengths
+	media.SizeLength, media.IndexLength, media.IndexDeltaLength = 6, 2, 0
+	_, medias = Parse(Marshal(Session{}, []Media{media}))
+	if got = medias[0]; got.SizeLength != 6 || got.IndexLength != 2 || got.IndexDeltaLength != 0 {
+		t.Fatalf("unexpected %+v", got)
+	}
+}
+
+func TestMarshalParseH265(t *testing.T) {
+	media := Media{
+		AVType:      "video",
+		Type:        av.H265,
+		TimeScale:   90000,
+		PayloadType: 96,
+		SpropVPS:    [][]byte{{0x40, 0x01, 0x0c}},
+		SpropSPS:    [][]byte{{0x42, 0x01, 0x01}},
+		SpropPPS:    [][]byte{{0x44, 0x01, 0xc1}},
+	}
+
+	_, medias := Parse(Marshal(Session{}, []Media{media}))
+	if len(medias) != 1 {
+		t.Fatalf("got %d medias", len(medias))
+	}
+	got := medias[0]
+	if got.Type != av.H265 || got.TimeScale != 90000 {
+		t.Fatalf("unexpected %+v", got)
+	}
+	if len(got.SpropVPS) != 1 || !bytes.Equal(got.SpropVPS[0], media.SpropVPS[0]) ||
+		len(got.SpropSPS) != 1 || !bytes.Equal(got.SpropSPS[0], media.SpropSPS[0]) ||
+		len(got.SpropPPS) != 1 || !bytes.Equal(got.SpropPPS[0], media.SpropPPS[0]) {
+		t.Fatalf("unexpected %+v", got)
+	}
+}
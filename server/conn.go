@@ -0,0 +1,490 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/packetizer"
+	"github.com/fanap-infra/rtsp/utils"
+)
+
+const sessionTimeout = 60
+
+// maxBodySize bounds the request body a client may announce.
+const maxBodySize = 64 << 10
+
+var statusText = map[int]string{
+	200: "OK",
+	400: "Bad Request",
+	404: "Not Found",
+	454: "Session Not Found",
+	455: "Method Not Valid in This State",
+	461: "Unsupported Transport",
+	500: "Internal Server Error",
+	501: "Not Implemented",
+}
+
+type request struct {
+	Method string
+	Uri    string
+	Header textproto.MIMEHeader
+	Body   []byte
+}
+
+// track is one stream of the publication set up by the client.
+type track struct {
+	codec      av.CodecData
+	packetizer packetizer.Packetizer
+	channel    int
+
+	rtp        *net.UDPConn
+	rtcp       *net.UDPConn
+	clientRtp  *net.UDPAddr
+	clientRtcp *net.UDPAddr
+
+	waitKeyFrame bool
+}
+
+func (self *track) isVideo() bool {
+	typ := self.codec.Type()
+	return !typ.IsAudio() && !typ.IsMetadata()
+}
+
+func (self *track) close() {
+	if self.rtp != nil {
+		self.rtp.Close()
+		self.rtcp.Close()
+	}
+}
+
+// conn serves one RTSP connection, which carries at most one session.
+type conn struct {
+	server *Server
+	nc     net.Conn
+	br     *bufio.Reader
+	wlock  sync.Mutex
+
+	session string
+	pub     *Publication
+	tracks  map[int]*track
+	playing bool
+
+	packets   chan av.Packet
+	dropped   int32
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newConn(server *Server, nc net.Conn) *conn {
+	return &conn{
+		server:  server,
+		nc:      nc,
+		br:      bufio.NewReaderSize(nc, 4096),
+		tracks:  map[int]*track{},
+		packets: make(chan av.Packet, 256),
+		done:    make(chan struct{}),
+	}
+}
+
+// close ends the connection, from any goroutine. The serve goroutine, the
+// only one writing pub and tracks, tears the session down.
+func (self *conn) close() {
+	self.closeOnce.Do(func() {
+		close(self.done)
+		self.nc.Close()
+	})
+}
+
+func (self *conn) teardown() {
+	self.close()
+	if self.pub != nil {
+		self.pub.removeConn(self)
+	}
+	for _, t := range self.tracks {
+		t.close()
+	}
+	self.server.removeConn(self)
+}
+
+func (self *conn) serve() {
+	defer self.teardown()
+	for {
+		req, err := self.readRequest()
+		if err != nil {
+			if err != io.EOF {
+				logRTSP.Debugv("rtsp: read request", "remote", self.nc.RemoteAddr(), "err", err)
+			}
+			return
+		}
+		logRTSP.Debugv("rtsp: request", "method", req.Method, "uri", req.Uri)
+		if err = self.handleRequest(req); err != nil {
+			return
+		}
+	}
+}
+
+func (self *conn) readRequest() (req request, err error) {
+	if self.server.RtspTimeout > 0 {
+		self.nc.SetReadDeadline(time.Now().Add(self.server.RtspTimeout))
+	}
+
+	// skip interleaved RTCP sent by the client
+	for {
+		var b []byte
+		if b, err = self.br.Peek(1); err != nil {
+			return
+		}
+		if b[0] != '$' {
+			break
+		}
+		var h [4]byte
+		if _, err = io.ReadFull(self.br, h[:]); err != nil {
+			return
+		}
+		if _, err = self.br.Discard(int(binary.BigEndian.Uint16(h[2:4]))); err != nil {
+			return
+		}
+	}
+
+	r := textproto.NewReader(self.br)
+	var line string
+	for line == "" {
+		if line, err = r.ReadLine(); err != nil {
+			return
+		}
+	}
+	fields := strings.SplitN(line, " ", 3)
+	if len(fields) != 3 || !strings.HasPrefix(fields[2], "RTSP/") {
+		err = fmt.Errorf("rtsp: request line invalid: %q", line)
+		return
+	}
+	req.Method = fields[0]
+	req.Uri = fields[1]
+	if req.Header, err = r.ReadMIMEHeader(); err != nil {
+		return
+	}
+	if n, _ := strconv.Atoi(req.Header.Get("Content-Length")); n > 0 {
+		if n > maxBodySize {
+			self.writeResponse(req, 400, nil, nil)
+			err = fmt.Errorf("rtsp: content length=%d too large", n)
+			return
+		}
+		req.Body = make([]byte, n)
+		if _, err = io.ReadFull(self.br, req.Body); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (self *conn) write(b []byte) (err error) {
+	self.wlock.Lock()
+	defer self.wlock.Unlock()
+	if self.server.WriteTimeout > 0 {
+		self.nc.SetWriteDeadline(time.Now().Add(self.server.WriteTimeout))
+	}
+	_, err = self.nc.Write(b)
+	return
+}
+
+func (self *conn) writeResponse(req request, status int, headers []string, body []byte) (err error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "RTSP/1.0 %d %s\r\n", status, statusText[status])
+	fmt.Fprintf(buf, "CSeq: %s\r\n", req.Header.Get("CSeq"))
+	if self.session != "" {
+		fmt.Fprintf(buf, "Session: %s;timeout=%d\r\n", self.session, sessionTimeout)
+	}
+	for _, h := range headers {
+		io.WriteString(buf, h)
+		io.WriteString(buf, "\r\n")
+	}
+	if len(body) > 0 {
+		fmt.Fprintf(buf, "Content-Length: %d\r\n", len(body))
+	}
+	io.WriteString(buf, "\r\n")
+	buf.Write(body)
+
+	logRTSP.Debug("> ", buf.String())
+	return self.write(buf.Bytes())
+}
+
+// parseUri splits a request URI into the publication path and, for SETUP,
+// the stream index from the "streamid=N" control suffix (-1 when absent).
+func parseUri(uri string) (path string, idx int) {
+	idx = -1
+	if u, err := url.Parse(uri); err == nil {
+		path = u.Path
+	}
+	path = normalizePath(path)
+	i := strings.LastIndex(path, "streamid=")
+	if i >= 0 && (i == 0 || path[i-1] == '/') {
+		if n, err := strconv.Atoi(path[i+len("streamid="):]); err == nil {
+			idx = n
+			path = normalizePath(path[:i])
+		}
+	}
+	return
+}
+
+func (self *conn) handleRequest(req request) (err error) {
+	if self.session != "" {
+		if sess := req.Header.Get("Session"); sess != "" && strings.Split(sess, ";")[0] != self.session {
+			return self.writeResponse(req, 454, nil, nil)
+		}
+	}
+
+	switch req.Method {
+	case "OPTIONS":
+		return self.writeResponse(req, 200, []string{"Public: OPTIONS, DESCRIBE, SETUP, PLAY, TEARDOWN, GET_PARAMETER"}, nil)
+
+	case "GET_PARAMETER", "SET_PARAMETER":
+		return self.writeResponse(req, 200, nil, nil)
+
+	case "DESCRIBE":
+		path, _ := parseUri(req.Uri)
+		pub := self.server.lookup(path)
+		if pub == nil {
+			return self.writeResponse(req, 404, nil, nil)
+		}
+		return self.writeResponse(req, 200, []string{
+			"Content-Type: application/sdp",
+			"Content-Base: " + strings.TrimRight(req.Uri, "/") + "/",
+		}, []byte(pub.sdp()))
+
+	case "SETUP":
+		return self.handleSetup(req)
+
+	case "PLAY":
+		return self.handlePlay(req)
+
+	case "TEARDOWN":
+		self.writeResponse(req, 200, nil, nil)
+		return io.EOF
+
+	default:
+		return self.writeResponse(req, 501, nil, nil)
+	}
+}
+
+func newSessionId() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (self *conn) handleSetup(req request) (err error) {
+	if self.playing {
+		return self.writeResponse(req, 455, nil, nil)
+	}
+
+	path, idx := parseUri(req.Uri)
+	pub := self.server.lookup(path)
+	if pub != nil && idx == -1 && len(pub.streams) == 1 {
+		idx = 0
+	}
+	if pub == nil || idx < 0 || idx >= len(pub.streams) {
+		return self.writeResponse(req, 404, nil, nil)
+	}
+	if self.pub != nil && self.pub != pub {
+		return self.writeResponse(req, 455, nil, nil)
+	}
+
+	th := parseTransportHeader(req.Header.Get("Transport"))
+	t := &track{codec: pub.streams[idx]}
+	if t.packetizer, err = packetizer.New(pub.medias[idx], t.codec, self.server.MTU); err != nil {
+		return self.writeResponse(req, 500, nil, nil)
+	}
+	t.waitKeyFrame = t.isVideo()
+
+	var transport string
+	switch {
+	case th.Multicast:
+		return self.writeResponse(req, 461, nil, nil)
+
+	case th.TCP:
+		t.channel = idx * 2
+		if th.Interleaved[0] >= 0 {
+			t.channel = th.Interleaved[0]
+		}
+		transport = fmt.Sprintf("RTP/AVP/TCP;unicast;interleaved=%d-%d", t.channel, t.channel+1)
+
+	case th.ClientPort[0] > 0:
+		addr, ok := self.nc.RemoteAddr().(*net.TCPAddr)
+		if !ok {
+			return self.writeResponse(req, 461, nil, nil)
+		}
+		ip := addr.IP
+		t.clientRtp = &net.UDPAddr{IP: ip, Port: th.ClientPort[0]}
+		t.clientRtcp = &net.UDPAddr{IP: ip, Port: th.ClientPort[1]}
+		if t.rtp, t.rtcp, err = utils.ListenUDPPair(); err != nil {
+			return self.writeResponse(req, 500, nil, nil)
+		}
+		port := t.rtp.LocalAddr().(*net.UDPAddr).Port
+		transport = fmt.Sprintf("RTP/AVP;unicast;client_port=%d-%d;server_port=%d-%d",
+			th.ClientPort[0], th.ClientPort[1], port, port+1)
+
+	default:
+		return self.writeResponse(req, 461, nil, nil)
+	}
+
+	if old := self.tracks[idx]; old != nil {
+		old.close()
+	}
+	self.tracks[idx] = t
+	self.pub = pub
+	if self.session == "" {
+		self.session = newSessionId()
+	}
+	return self.writeResponse(req, 200, []string{"Transport: " + transport}, nil)
+}
+
+func (self *conn) handlePlay(req request) (err error) {
+	if self.session == "" || len(self.tracks) == 0 {
+		return self.writeResponse(req, 455, nil, nil)
+	}
+	if self.playing {
+		return self.writeResponse(req, 200, nil, nil)
+	}
+	if err = self.writeResponse(req, 200, []string{"Range: npt=0.000-"}, nil); err != nil {
+		return
+	}
+	if err = self.pub.addConn(self); err != nil {
+		return
+	}
+	self.playing = true
+	go self.writeLoop()
+	return
+}
+
+// queuePacket never blocks the publisher, a client that falls behind loses
+// packets and waits for the next key frame.
+func (self *conn) queuePacket(pkt av.Packet) {
+	select {
+	case self.packets <- pkt:
+	default:
+		atomic.StoreInt32(&self.dropped, 1)
+	}
+}
+
+func (self *conn) writeLoop() {
+	for {
+		select {
+		case pkt := <-self.packets:
+			if err := self.writePacket(pkt); err != nil {
+				logRTSP.Debugv("rtsp: write packet", "remote", self.nc.RemoteAddr(), "err", err)
+				self.close()
+				return
+			}
+		case <-self.done:
+			return
+		}
+	}
+}
+
+func (self *conn) writePacket(pkt av.Packet) (err error) {
+	if atomic.SwapInt32(&self.dropped, 0) == 1 {
+		for _, t := range self.tracks {
+			t.waitKeyFrame = t.isVideo()
+		}
+	}
+
+	t := self.tracks[int(pkt.Idx)]
+	if t == nil {
+		return
+	}
+	if t.waitKeyFrame {
+		if !pkt.IsKeyFrame {
+			return
+		}
+		t.waitKeyFrame = false
+	}
+
+	var packets [][]byte
+	if packets, err = t.packetizer.Packetize(pkt); err != nil {
+		return
+	}
+	for _, b := range packets {
+		if t.rtp != nil {
+			if _, err = t.rtp.WriteToUDP(b, t.clientRtp); err != nil {
+				return
+			}
+			continue
+		}
+		frame := make([]byte, 4+len(b))
+		frame[0] = '$'
+		frame[1] = byte(t.channel)
+		binary.BigEndian.PutUint16(frame[2:4], uint16(len(b)))
+		copy(frame[4:], b)
+		if err = self.write(frame); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// transportHeader holds the parts of the client's Transport header the
+// server acts on.
+type transportHeader struct {
+	TCP         bool
+	Multicast   bool
+	Interleaved [2]int
+	ClientPort  [2]int
+	Mode        string
+}
+
+func parseTransportHeader(val string) (th transportHeader) {
+	th.Interleaved = [2]int{-1, -1}
+	if i := strings.Index(val, ","); i >= 0 {
+		val = val[:i]
+	}
+	for i, field := range strings.Split(val, ";") {
+		field = strings.TrimSpace(field)
+		if i == 0 {
+			th.TCP = strings.HasSuffix(strings.ToUpper(field), "/TCP")
+			continue
+		}
+		keyval := strings.SplitN(field, "=", 2)
+		switch strings.ToLower(keyval[0]) {
+		case "multicast":
+			th.Multicast = true
+		case "interleaved":
+			if len(keyval) == 2 {
+				th.Interleaved = parsePortRange(keyval[1])
+			}
+		case "client_port":
+			if len(keyval) == 2 {
+				th.ClientPort = parsePortRange(keyval[1])
+			}
+		case "mode":
+			if len(keyval) == 2 {
+				th.Mode = strings.ToLower(strings.Trim(keyval[1], `"`))
+			}
+		}
+	}
+	return
+}
+
+func parsePortRange(val string) (ports [2]int) {
+	fields := strings.SplitN(val, "-", 2)
+	ports[0], _ = strconv.Atoi(fields[0])
+	if len(fields) == 2 {
+		ports[1], _ = strconv.Atoi(fields[1])
+	} else {
+		ports[1] = ports[0] + 1
+	}
+	return
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/sdp"
+)
+
+// Publication is a set of streams published under a path. It implements
+// av.PacketWriter, so any live source can be copied into it.
+type Publication struct {
+	server  *Server
+	path    string
+	streams []av.CodecData
+	medias  []sdp.Media
+
+	lock   sync.RWMutex
+	conns  map[*conn]bool
+	closed bool
+}
+
+func newPublication(server *Server, path string, streams []av.CodecData) (self *Publication, err error) {
+	self = &Publication{
+		server:  server,
+		path:    path,
+		streams: streams,
+		conns:   map[*conn]bool{},
+	}
+	for i, codec := range streams {
+		var media sdp.Media
+		if media, err = sdp.NewMediaFromCodecData(codec, 96+i); err != nil {
+			return
+		}
+		media.Control = fmt.Sprintf("streamid=%d", i)
+		self.medias = append(self.medias, media)
+	}
+	return
+}
+
+func (self *Publication) Path() string {
+	return self.path
+}
+
+func (self *Publication) Streams() []av.CodecData {
+	return self.streams
+}
+
+func (self *Publication) sdp() string {
+	return sdp.Marshal(sdp.Session{}, self.medias)
+}
+
+func (self *Publication) addConn(c *conn) (err error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.closed {
+		err = fmt.Errorf("rtsp: publication %q closed", self.path)
+		return
+	}
+	self.conns[c] = true
+	return
+}
+
+func (self *Publication) removeConn(c *conn) {
+	self.lock.Lock()
+	delete(self.conns, c)
+	self.lock.Unlock()
+}
+
+// WritePacket queues pkt for every playing client, pkt.Idx selects the stream.
+func (self *Publication) WritePacket(pkt av.Packet) (err error) {
+	if int(pkt.Idx) >= len(self.streams) {
+		err = fmt.Errorf("rtsp: stream index=%d invalid", pkt.Idx)
+		return
+	}
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	if self.closed {
+		err = fmt.Errorf("rtsp: publication %q closed", self.path)
+		return
+	}
+	for c := range self.conns {
+		c.queuePacket(pkt)
+	}
+	return
+}
+
+// Close unpublishes the path and disconnects its clients.
+func (self *Publication) Close() (err error) {
+	self.lock.Lock()
+	if self.closed {
+		self.lock.Unlock()
+		return
+	}
+	self.closed = true
+	var conns []*conn
+	for c := range self.conns {
+		conns = append(conns, c)
+	}
+	self.lock.Unlock()
+
+	self.server.unpublish(self)
+	for _, c := range conns {
+		c.close()
+	}
+	return
+}
@@ -0,0 +1,185 @@
+// Package server is an embeddable RTSP server that re-serves av sources to
+// RTSP clients over TCP interleaved or UDP unicast.
+package server
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fanap-infra/log"
+	"github.com/fanap-infra/rtsp/av"
+)
+
+var logRTSP = log.GetScope("RTSPServer")
+
+type Server struct {
+	// Addr to listen on with ListenAndServe, ":554" when empty.
+	Addr string
+
+	// RtspTimeout closes connections that send no request for this long,
+	// clients keep the session alive with OPTIONS or GET_PARAMETER.
+	// Zero disables it.
+	RtspTimeout time.Duration
+	// WriteTimeout bounds each write of a response or media packet.
+	WriteTimeout time.Duration
+	// MTU limits the size of the RTP packets sent, packetizer.DefaultMTU
+	// when zero.
+	MTU int
+
+	lock   sync.RWMutex
+	ln     net.Listener
+	paths  map[string]*Publication
+	conns  map[*conn]bool
+	closed bool
+}
+
+func normalizePath(path string) string {
+	return strings.Trim(path, "/")
+}
+
+func (self *Server) ListenAndServe() (err error) {
+	addr := self.Addr
+	if addr == "" {
+		addr = ":554"
+	}
+	var ln net.Listener
+	if ln, err = net.Listen("tcp", addr); err != nil {
+		return
+	}
+	return self.Serve(ln)
+}
+
+// Serve accepts RTSP connections on ln until Close is called.
+func (self *Server) Serve(ln net.Listener) (err error) {
+	self.lock.Lock()
+	if self.closed {
+		self.lock.Unlock()
+		ln.Close()
+		return fmt.Errorf("rtsp: server closed")
+	}
+	self.ln = ln
+	self.lock.Unlock()
+
+	for {
+		var nc net.Conn
+		if nc, err = ln.Accept(); err != nil {
+			self.lock.RLock()
+			closed := self.closed
+			self.lock.RUnlock()
+			if closed {
+				err = nil
+			}
+			return
+		}
+		c := newConn(self, nc)
+		self.lock.Lock()
+		if self.conns == nil {
+			self.conns = map[*conn]bool{}
+		}
+		self.conns[c] = true
+		self.lock.Unlock()
+		go c.serve()
+	}
+}
+
+// Close stops listening and ends every connection and publication.
+func (self *Server) Close() (err error) {
+	self.lock.Lock()
+	self.closed = true
+	ln := self.ln
+	var conns []*conn
+	for c := range self.conns {
+		conns = append(conns, c)
+	}
+	var pubs []*Publication
+	for _, pub := range self.paths {
+		pubs = append(pubs, pub)
+	}
+	self.lock.Unlock()
+
+	if ln != nil {
+		err = ln.Close()
+	}
+	for _, c := range conns {
+		c.close()
+	}
+	for _, pub := range pubs {
+		pub.Close()
+	}
+	return
+}
+
+func (self *Server) removeConn(c *conn) {
+	self.lock.Lock()
+	delete(self.conns, c)
+	self.lock.Unlock()
+}
+
+// Publish makes streams available to clients as rtsp://host/path. Packets
+// written to the returned Publication are sent to every playing client.
+func (self *Server) Publish(path string, streams []av.CodecData) (pub *Publication, err error) {
+	path = normalizePath(path)
+	if pub, err = newPublication(self, path, streams); err != nil {
+		return
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if _, ok := self.paths[path]; ok {
+		err = fmt.Errorf("rtsp: path %q already published", path)
+		return
+	}
+	if self.paths == nil {
+		self.paths = map[string]*Publication{}
+	}
+	self.paths[path] = pub
+	return
+}
+
+// PublishDemuxer publishes the streams of demuxer and copies its packets in
+// real time, paced by av.Packet.Time, until ReadPacket fails.
+func (self *Server) PublishDemuxer(path string, demuxer av.Demuxer) (pub *Publication, err error) {
+	var streams []av.CodecData
+	if streams, err = demuxer.Streams(); err != nil {
+		return
+	}
+	if pub, err = self.Publish(path, streams); err != nil {
+		return
+	}
+
+	go func() {
+		start := time.Now()
+		for {
+			pkt, err := demuxer.ReadPacket()
+			if err != nil {
+				logRTSP.Debugv("rtsp: demuxer done", "path", path, "err", err)
+				break
+			}
+			if wait := pkt.Time - time.Since(start); wait > 0 {
+				time.Sleep(wait)
+			}
+			if err = pub.WritePacket(pkt); err != nil {
+				break
+			}
+		}
+		pub.Close()
+	}()
+	return
+}
+
+func (self *Server) unpublish(pub *Publication) {
+	self.lock.Lock()
+	if self.paths[pub.path] == pub {
+		delete(self.paths, pub.path)
+	}
+	self.lock.Unlock()
+}
+
+func (self *Server) lookup(path string) *Publication {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	return self.paths[path]
+}
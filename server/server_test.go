@@ -0,0 +1,166 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fanap-infra/rtsp/av"
+	"github.com/fanap-infra/rtsp/client"
+	"github.com/fanap-infra/rtsp/codec"
+	"github.com/fanap-infra/rtsp/codec/h264parser"
+	"github.com/fanap-infra/rtsp/utils/bits/pio"
+)
+
+func testH264CodecData(t *testing.T) h264parser.CodecData {
+	sps, _ := base64.StdEncoding.DecodeString("Z00AHpWoKA9k")
+	pps, _ := base64.StdEncoding.DecodeString("aO48gA==")
+	h264, err := h264parser.NewCodecDataFromSPSAndPPS(sps, pps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h264
+}
+
+func startTestServer(t *testing.T) (*Server, string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &Server{}
+	go srv.Serve(ln)
+	return srv, "rtsp://" + ln.Addr().String()
+}
+
+func TestServerPlay(t *testing.T) {
+	for _, transport := range []client.Transport{client.TransportTCP, client.TransportUDP} {
+		srv, url := startTestServer(t)
+
+		pub, err := srv.Publish("live/cam1", []av.CodecData{testH264CodecData(t), codec.NewPCMMulawCodecData()})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// an IDR slice large enough to need FU-A
+		idr := make([]byte, 5000)
+		idr[0] = 0x65
+		for i := 1; i < len(idr); i++ {
+			idr[i] = byte(i)
+		}
+		video := make([]byte, 4+len(idr))
+		pio.PutU32BE(video, uint32(len(idr)))
+		copy(video[4:], idr)
+		audio := bytes.Repeat([]byte{0x7f}, 160)
+
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				case <-time.After(10 * time.Millisecond):
+				}
+				tm := time.Duration(i) * 40 * time.Millisecond
+				pub.WritePacket(av.Packet{Idx: 0, IsKeyFrame: true, Time: tm, Data: video})
+				pub.WritePacket(av.Packet{Idx: 1, Time: tm, Data: audio})
+			}
+		}()
+
+		cli, err := client.Dial(url + "/live/cam1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cli.Transport = transport
+		cli.RtspTimeout = 5 * time.Second
+		cli.RtpTimeout = 5 * time.Second
+
+		streams, err := cli.Streams()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(streams) != 2 || streams[0].Type() != av.H264 || streams[1].Type() != av.PCM_MULAW {
+			t.Fatalf("%v: unexpected streams %v", transport, streams)
+		}
+
+		var gotVideo, gotAudio bool
+		for !gotVideo || !gotAudio {
+			pkt, err := cli.ReadPacket()
+			if err != nil {
+				t.Fatalf("%v: %s", transport, err)
+			}
+			switch pkt.Idx {
+			case 0:
+				if !bytes.Equal(pkt.Data, video) || !pkt.IsKeyFrame {
+					t.Fatalf("%v: video packet mismatch len=%d", transport, len(pkt.Data))
+				}
+				gotVideo = true
+			case 1:
+				if !bytes.Equal(pkt.Data, audio) {
+					t.Fatalf("%v: audio packet mismatch len=%d", transport, len(pkt.Data))
+				}
+				gotAudio = true
+			}
+		}
+
+		cli.Close()
+		srv.Close()
+	}
+}
+
+func TestServerDescribeNotFound(t *testing.T) {
+	srv, url := startTestServer(t)
+	defer srv.Close()
+
+	cli, err := client.Dial(url + "/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	cli.RtspTimeout = 5 * time.Second
+	if _, err = cli.Describe(); err == nil {
+		t.Fatal("describe of unpublished path succeeded")
+	}
+}
+
+func TestServerBodyTooLarge(t *testing.T) {
+	srv, url := startTestServer(t)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "rtsp://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(conn, "ANNOUNCE "+url+"/live RTSP/1.0\r\nCSeq: 1\r\nContent-Length: 2000000000\r\n\r\n")
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(line, "RTSP/1.0 400 ") {
+		t.Fatalf("response=%q", line)
+	}
+}
+
+func TestParseUri(t *testing.T) {
+	for _, c := range []struct {
+		uri  string
+		path string
+		idx  int
+	}{
+		{"rtsp://host/live/cam1", "live/cam1", -1},
+		{"rtsp://host/live/cam1/streamid=1", "live/cam1", 1},
+		{"rtsp://host/streamid=0", "", 0},
+	} {
+		if path, idx := parseUri(c.uri); path != c.path || idx != c.idx {
+			t.Fatalf("%s: got path=%q idx=%d", c.uri, path, idx)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"time"
+)
+
+// ntpEpochOffset is the number of seconds from the NTP epoch, 1900-01-01,
+// to the Unix epoch.
+const ntpEpochOffset = 2208988800
+
+// NTPToTime converts a 64-bit NTP timestamp, 32.32 fixed point seconds
+// since 1900, as carried by RTCP and RTP header extensions.
+func NTPToTime(ntp uint64) time.Time {
+	sec := int64(ntp>>32) - ntpEpochOffset
+	nsec := int64(((ntp&0xffffffff)*uint64(time.Second) + 1<<31) >> 32)
+	return time.Unix(sec, nsec).UTC()
+}
+
+// TimeToNTP is the inverse of NTPToTime.
+func TimeToNTP(t time.Time) uint64 {
+	sec := uint64(t.Unix() + ntpEpochOffset)
+	frac := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return sec<<32 | frac
+}
@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+)
+
+// ListenUDPPair opens two UDP sockets on consecutive ports, the first on an
+// even port as RFC 3550 section 11 requires for RTP, the second for RTCP.
+func ListenUDPPair() (rtp, rtcp *net.UDPConn, err error) {
+	for i := 0; i < 100; i++ {
+		if rtp, err = net.ListenUDP("udp", &net.UDPAddr{}); err != nil {
+			return
+		}
+		port := rtp.LocalAddr().(*net.UDPAddr).Port
+		if port%2 != 0 {
+			rtp.Close()
+			continue
+		}
+		if rtcp, err = net.ListenUDP("udp", &net.UDPAddr{Port: port + 1}); err != nil {
+			rtp.Close()
+			continue
+		}
+		return
+	}
+	err = fmt.Errorf("utils: no free udp port pair")
+	return
+}